@@ -41,6 +41,9 @@ type PushServerConfig struct {
 
 type RestServerConfig struct {
 	Addr string `json:"addr"`
+
+	// Users allowed to the admin api
+	AdminEmails []string `json:"adminEmails"`
 }
 
 type CORSConfig struct{
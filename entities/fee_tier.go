@@ -0,0 +1,22 @@
+package entities
+
+import (
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// FeeTier is a step of the fee schedule of a product. A user trades at the
+// tier with the highest MinVolume reached by their trailing 30 day volume.
+type FeeTier struct {
+	gorm.Model
+
+	// The tiers of product 0 apply to the products without their own
+	ProductId int64 `json:"product_id"`
+
+	// Trailing 30 day volume in the quote currency of the product
+	MinVolume decimal.Decimal `json:"min_volume"`
+
+	// A negative maker rate is a rebate
+	MakerRate decimal.Decimal `json:"maker_rate"`
+	TakerRate decimal.Decimal `json:"taker_rate"`
+}
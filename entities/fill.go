@@ -16,18 +16,31 @@ type Fill struct {
 	ProductId int64   `json:"product_id"`
 	Product   Product `gorm:"foreignKey:ProductId"`
 
-	Size      decimal.Decimal `json:"fill_size"`
-	Price     decimal.Decimal `json:"price"`
-	Funds     decimal.Decimal `json:"funds"`
+	Size  decimal.Decimal `json:"fill_size"`
+	Price decimal.Decimal `json:"price"`
+	Funds decimal.Decimal `json:"funds"`
+	// Fee in the currency the fill brings in, the base currency for a buy
+	// and the quote currency for a sell. Negative for a maker rebate. The
+	// rate is the one of the tier of the user when the trade was made
 	Fee       decimal.Decimal `json:"fees"`
+	FeeRate   decimal.Decimal `json:"fee_rate"`
 	Liquidity string          `json:"liquidity"`
 	Settled   bool            `json:"settled"`
 	Side      Side            `json:"side"`
 	Done      bool            `json:"done"`
 
+	// The fill records an amend of the order to Size and Price rather
+	// than a trade
+	Amend bool `json:"amend"`
+
 	DoneReason DoneReason `json:"done_reason"`
-	LogOffset  int64
-	LogSeq     int64
+
+	// The fill records the rejection of the order by the matching engine,
+	// which settles it like a done order
+	RejectReason RejectReason `json:"reject_reason"`
+
+	LogOffset int64
+	LogSeq    int64
 
 	MessageSeq int64 `gorm:"index:o_m unique"`
 }
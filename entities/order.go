@@ -1,6 +1,8 @@
 package entities
 
 import (
+	"time"
+
 	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
@@ -19,10 +21,49 @@ type Order struct {
 	FilledSize    decimal.Decimal `json:"filled_size"`
 	ExecutedValue decimal.Decimal `json:"executed_value"`
 	Price         decimal.Decimal `json:"price"`
+	StopPrice     decimal.Decimal `json:"stop_price"`
 	FillFees      decimal.Decimal `json:"fill_fees"`
 	Type          OrderType       `json:"order_type"`
 	Side          Side            `json:"side"`
-	TimeInForce   string          `json:"time_in_force"`
-	Status        OrderStatus     `json:"status"`
-	Settled       bool            `json:"settled"`
+	TimeInForce   TimeInForce     `json:"time_in_force"`
+	ExpireTime    *time.Time      `json:"expire_time"`
+	PostOnly      bool            `json:"post_only"`
+	DisplaySize   decimal.Decimal `json:"display_size"`
+	Hidden        bool            `json:"hidden"`
+
+	SelfTradePrevention SelfTradePrevention `json:"stp"`
+
+	// Price a pegged limit order tracks, plus the offset. The price of the
+	// order caps a buy and floors a sell
+	PegType   PegType         `json:"peg_type"`
+	PegOffset decimal.Decimal `json:"peg_offset"`
+
+	// Distance a trailing stop order keeps from the best trade price since
+	// it was placed, either an amount or a percentage of that price
+	TrailAmount  decimal.Decimal `json:"trail_amount"`
+	TrailPercent decimal.Decimal `json:"trail_percent"`
+
+	// Placed by a mass quote, which replaces all the quotes of the user on
+	// the product at once
+	Quote bool `json:"quote"`
+
+	// Group the order is linked to, zero if none. The legs of a group
+	// other than a bracket entry settle against the hold of the group
+	GroupId   int64          `json:"group_id"`
+	GroupRole OrderGroupRole `json:"group_role"`
+
+	Status  OrderStatus `json:"status"`
+	Settled bool        `json:"settled"`
+
+	// Why the matching engine rejected the order, empty unless rejected
+	RejectReason RejectReason `json:"reject_reason"`
+
+	// Set while an amend waits to be settled, only one amend of the
+	// order may be in flight at a time
+	AmendPending bool `json:"amend_pending"`
+
+	// What was held for the amend in flight on top of the hold of the
+	// order, in the funds of a buy or the size of a sell. It's released if
+	// the amend is refused
+	AmendHold decimal.Decimal `json:"amend_hold"`
 }
@@ -0,0 +1,24 @@
+package entities
+
+import (
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// OrderGroup links orders placed together. The legs of an OCO group share a
+// single hold, and the exits of a bracket are held from what the entry buys.
+type OrderGroup struct {
+	gorm.Model
+	UserId    int64          `json:"user_id"`
+	ProductId int64          `json:"product_id"`
+	Type      OrderGroupType `json:"type"`
+
+	// Held for the legs settling against the group. The fee of a bracket entry
+	// is paid from what it buys, so this may fall below zero until the last
+	// leg is done and the rest is released
+	HoldCurrency string          `json:"hold_currency"`
+	Hold         decimal.Decimal `json:"hold"`
+
+	// Legs settling against the hold which are not done yet
+	OpenLegs int `json:"open_legs"`
+}
@@ -16,4 +16,70 @@ type Product struct {
 	BaseScale      int32
 	QuoteScale     int32
 	QuoteIncrement float64
+	Status         ProductStatus
+
+	// Last trading status sent to the matching engine
+	TradingStatus TradingStatus
+
+	PriceLimits PriceLimits `gorm:"embedded"`
+
+	// How trades are shared between the orders at a price, FIFO if empty
+	MatchingAlgorithm MatchingAlgorithm
+}
+
+// PriceLimits protects a product against orders priced far away from the
+// market and against sudden moves of the trade price. A zero percentage turns
+// the limit off.
+type PriceLimits struct {
+	// How far in percent a limit price may be from the last trade price, or
+	// the mid price before the first trade. Market orders never trade
+	// further away than that.
+	PriceBandPercent decimal.Decimal `json:"priceBandPercent"`
+
+	// A reopening auction of CircuitBreakerHaltSeconds replaces the
+	// continuous trading once the trade price moves more than
+	// CircuitBreakerPercent within CircuitBreakerWindowSeconds
+	CircuitBreakerPercent       decimal.Decimal `json:"circuitBreakerPercent"`
+	CircuitBreakerWindowSeconds int             `json:"circuitBreakerWindowSeconds"`
+	CircuitBreakerHaltSeconds   int             `json:"circuitBreakerHaltSeconds"`
+}
+
+// PriceBand returns the lowest and the highest price allowed around the
+// reference price, ok is false if there is no band
+func (l *PriceLimits) PriceBand(referencePrice decimal.Decimal) (low, high decimal.Decimal, ok bool) {
+	if l.PriceBandPercent.LessThanOrEqual(decimal.Zero) || referencePrice.LessThanOrEqual(decimal.Zero) {
+		return decimal.Zero, decimal.Zero, false
+	}
+
+	delta := referencePrice.Mul(l.PriceBandPercent).Div(decimal.New(100, 0))
+	low = decimal.Max(referencePrice.Sub(delta), decimal.Zero)
+	high = referencePrice.Add(delta)
+	return low, high, true
+}
+
+// TickSize is the increment of prices, the quote increment or else the smallest
+// step of the quote scale
+func (p *Product) TickSize() decimal.Decimal {
+	if p.QuoteIncrement > 0 {
+		return decimal.NewFromFloat(p.QuoteIncrement)
+	}
+	return decimal.New(1, -p.QuoteScale)
+}
+
+// GetTradingStatus returns the trading status, open for the products which
+// never had one
+func (p *Product) GetTradingStatus() TradingStatus {
+	if len(p.TradingStatus) == 0 {
+		return TradingStatusOpen
+	}
+	return p.TradingStatus
+}
+
+// GetMatchingAlgorithm returns the matching algorithm, FIFO for the products
+// which never had one
+func (p *Product) GetMatchingAlgorithm() MatchingAlgorithm {
+	if len(p.MatchingAlgorithm) == 0 {
+		return MatchingAlgorithmFifo
+	}
+	return p.MatchingAlgorithm
 }
@@ -34,14 +34,46 @@ func (s Side) String() string {
 type OrderType string
 
 const (
-	MARKET_ORDER OrderType = "MARKET"
-	LIMIT_ORDER  OrderType = "LIMIT"
+	MARKET_ORDER      OrderType = "MARKET"
+	LIMIT_ORDER       OrderType = "LIMIT"
+	STOP_MARKET_ORDER OrderType = "STOP_MARKET"
+	STOP_LIMIT_ORDER  OrderType = "STOP_LIMIT"
 )
 
 func (t OrderType) String() string {
 	return string(t)
 }
 
+func NewOrderTypeFromString(s string) (*OrderType, error) {
+	orderType := OrderType(s)
+	switch orderType {
+	case MARKET_ORDER:
+	case LIMIT_ORDER:
+	case STOP_MARKET_ORDER:
+	case STOP_LIMIT_ORDER:
+	default:
+		return nil, fmt.Errorf("invalid order type: %v", s)
+	}
+	return &orderType, nil
+}
+
+// IsStop reports whether orders of this type wait in the trigger book until
+// the trade price crosses their stop price.
+func (t OrderType) IsStop() bool {
+	return t == STOP_MARKET_ORDER || t == STOP_LIMIT_ORDER
+}
+
+// Triggered returns the order type a stop order turns into once activated.
+func (t OrderType) Triggered() OrderType {
+	switch t {
+	case STOP_MARKET_ORDER:
+		return MARKET_ORDER
+	case STOP_LIMIT_ORDER:
+		return LIMIT_ORDER
+	}
+	return t
+}
+
 type OrderStatus string
 
 const (
@@ -50,6 +82,14 @@ const (
 	OrderStatusOpen       OrderStatus = "OPEN"
 	OrderStatusCancelling OrderStatus = "CANCELLING"
 	OrderStatusFilled     OrderStatus = "FILLED"
+
+	// Turned away by the matching engine without ever being taken, what was
+	// held for the order is released
+	OrderStatusRejected OrderStatus = "REJECTED"
+
+	// Only used on the order topic, asks the matching engine to change the
+	// price and size of a resting order
+	OrderStatusAmending OrderStatus = "AMENDING"
 )
 
 func NewOrderStatusFromString(s string) (*OrderStatus, error) {
@@ -60,12 +100,159 @@ func NewOrderStatusFromString(s string) (*OrderStatus, error) {
 	case OrderStatusCancelling:
 	case OrderStatusCancelled:
 	case OrderStatusFilled:
+	case OrderStatusRejected:
+	case OrderStatusAmending:
 	default:
 		return nil, fmt.Errorf("invalid status:%v", s)
 	}
 	return &status, nil
 }
 
+type ProductStatus string
+
+const (
+	// Rows written before the status existed have none, they are online
+	ProductStatusOnline   ProductStatus = "ONLINE"
+	ProductStatusPaused   ProductStatus = "PAUSED"
+	ProductStatusDelisted ProductStatus = "DELISTED"
+)
+
+// AcceptsOrders tells whether new orders and amends can be placed, cancels are
+// accepted until the product is delisted
+func (s ProductStatus) AcceptsOrders() bool {
+	return s == "" || s == ProductStatusOnline
+}
+
+// TradingStatus is what the matching engine of a product accepts
+type TradingStatus string
+
+const (
+	// Rows and snapshots written before the trading status existed have
+	// none, they are open
+	TradingStatusOpen TradingStatus = "open"
+
+	// New orders and amends are rejected, only cancels are accepted
+	TradingStatusHalted TradingStatus = "halted"
+
+	// Only cancels are accepted
+	TradingStatusCancelOnly TradingStatus = "cancelOnly"
+
+	// Limit orders are accepted as post-only, nothing trades
+	TradingStatusPostOnly TradingStatus = "postOnly"
+
+	// Market orders are rejected
+	TradingStatusLimitOnly TradingStatus = "limitOnly"
+
+	// Limit orders rest on the book without matching, and trade at a single
+	// price once the auction ends
+	TradingStatusAuction TradingStatus = "auction"
+)
+
+func NewTradingStatusFromString(s string) (*TradingStatus, error) {
+	status := TradingStatus(s)
+	switch status {
+	case TradingStatusOpen:
+	case TradingStatusHalted:
+	case TradingStatusCancelOnly:
+	case TradingStatusPostOnly:
+	case TradingStatusLimitOnly:
+	case TradingStatusAuction:
+	default:
+		return nil, fmt.Errorf("invalid trading status: %v", s)
+	}
+	return &status, nil
+}
+
+// StatusReason tells why the trading status of a product changed
+type StatusReason string
+
+const (
+	// Changed by a product control command
+	StatusReasonControl StatusReason = "CONTROL"
+
+	// Reopening auction started by the circuit breaker of the product
+	StatusReasonCircuitBreaker StatusReason = "CIRCUIT_BREAKER"
+
+	// The end time of the auction has passed
+	StatusReasonAuctionEnd StatusReason = "AUCTION_END"
+)
+
+type TimeInForce string
+
+const (
+	// Good till cancelled
+	TimeInForceGTC TimeInForce = "GTC"
+	// Immediate or cancel
+	TimeInForceIOC TimeInForce = "IOC"
+	// Fill or kill
+	TimeInForceFOK TimeInForce = "FOK"
+	// Good till time
+	TimeInForceGTT TimeInForce = "GTT"
+)
+
+func NewTimeInForceFromString(s string) (*TimeInForce, error) {
+	timeInForce := TimeInForce(s)
+	switch timeInForce {
+	case TimeInForceGTC:
+	case TimeInForceIOC:
+	case TimeInForceFOK:
+	case TimeInForceGTT:
+	default:
+		return nil, fmt.Errorf("invalid time in force: %v", s)
+	}
+	return &timeInForce, nil
+}
+
+type SelfTradePrevention string
+
+const (
+	// Cancel the remaining of the incoming order
+	SelfTradePreventionCancelNewest SelfTradePrevention = "CANCEL_NEWEST"
+	// Cancel the resting order and keep matching the incoming one
+	SelfTradePreventionCancelOldest SelfTradePrevention = "CANCEL_OLDEST"
+	// Cancel both orders
+	SelfTradePreventionCancelBoth SelfTradePrevention = "CANCEL_BOTH"
+	// Decrement both orders by the smaller size, the smaller one is cancelled
+	SelfTradePreventionDecrementAndCancel SelfTradePrevention = "DECREMENT_AND_CANCEL"
+)
+
+// PegType is the price a pegged order tracks, relative to the side of the order
+type PegType string
+
+const (
+	// The best price of the side of the order, the best bid for a buy
+	PegTypePrimary PegType = "PRIMARY"
+	// The best price of the opposite side, the best ask for a buy
+	PegTypeMarket PegType = "MARKET"
+	// The middle of the best bid and the best ask
+	PegTypeMid PegType = "MID"
+)
+
+func NewPegTypeFromString(s string) (*PegType, error) {
+	pegType := PegType(s)
+	switch pegType {
+	case PegTypePrimary:
+	case PegTypeMarket:
+	case PegTypeMid:
+	default:
+		return nil, fmt.Errorf("invalid peg type: %v", s)
+	}
+	return &pegType, nil
+}
+
+func NewSelfTradePreventionFromString(s string) (*SelfTradePrevention, error) {
+	stp := SelfTradePrevention(s)
+	switch stp {
+	case SelfTradePreventionCancelNewest:
+	case SelfTradePreventionCancelOldest:
+	case SelfTradePreventionCancelBoth:
+	case SelfTradePreventionDecrementAndCancel:
+	default:
+		return nil, fmt.Errorf("invalid self trade prevention: %v", s)
+	}
+	return &stp, nil
+}
+
 type BillType string
 
 type DoneReason string
@@ -74,8 +261,106 @@ type TransactionStatus string
 
 const (
 	BillTypeTrade              BillType          = "TRADE"
+	BillTypeFee                BillType          = "FEE"
 	DoneReasonFilled           DoneReason        = "FILLED"
 	DoneReasonCancelled        DoneReason        = "CANCELLED"
+	DoneReasonExpired          DoneReason        = "EXPIRED"
+	DoneReasonPostOnly         DoneReason        = "POST_ONLY"
+	DoneReasonSelfTrade        DoneReason        = "SELF_TRADE"
+	DoneReasonTradingStatus    DoneReason        = "TRADING_STATUS"
+	DoneReasonPriceBand        DoneReason        = "PRICE_BAND"
+	DoneReasonDuplicate        DoneReason        = "DUPLICATE"
 	TransactionStatusPending   TransactionStatus = "PENDING"
 	TransactionStatusCompleted TransactionStatus = "COMPLETED"
 )
+
+// RejectReason tells why the matching engine turned an order or an amend away
+// without taking it
+type RejectReason string
+
+const (
+	// The order comes too late for the engine to tell it from a replay
+	RejectReasonDuplicate RejectReason = "DUPLICATE"
+
+	// The type or the side of the order is unknown to the engine
+	RejectReasonUnknownType RejectReason = "UNKNOWN_TYPE"
+
+	// Trading of the product is halted
+	RejectReasonHalted RejectReason = "HALTED"
+
+	// The amended order is no longer on the book
+	RejectReasonNotFound RejectReason = "NOT_FOUND"
+
+	// The amended order doesn't tell its total size, so what is filled of
+	// it is unknown
+	RejectReasonUnknownSize RejectReason = "UNKNOWN_SIZE"
+
+	// The order couldn't be sent to the engine
+	RejectReasonNotSent RejectReason = "NOT_SENT"
+
+	// The amend of a post-only order would have it take liquidity
+	RejectReasonPostOnly RejectReason = "POST_ONLY"
+)
+
+// OrderGroupType is how the orders of a group are linked
+type OrderGroupType string
+
+const (
+	// A trade or the end of a leg cancels the other legs
+	OrderGroupTypeOco OrderGroupType = "OCO"
+	// The entry arms a take-profit and a stop-loss once done, which are
+	// then linked as an OCO group
+	OrderGroupTypeBracket OrderGroupType = "BRACKET"
+)
+
+func NewOrderGroupTypeFromString(s string) (*OrderGroupType, error) {
+	groupType := OrderGroupType(s)
+	switch groupType {
+	case OrderGroupTypeOco:
+	case OrderGroupTypeBracket:
+	default:
+		return nil, fmt.Errorf("invalid order group type: %v", s)
+	}
+	return &groupType, nil
+}
+
+// OrderGroupRole is the part an order plays in its group
+type OrderGroupRole string
+
+const (
+	OrderGroupRoleLeg        OrderGroupRole = "LEG"
+	OrderGroupRoleEntry      OrderGroupRole = "ENTRY"
+	OrderGroupRoleTakeProfit OrderGroupRole = "TAKE_PROFIT"
+	OrderGroupRoleStopLoss   OrderGroupRole = "STOP_LOSS"
+)
+
+// MatchingAlgorithm is how a trade is shared between the orders resting at the
+// price it trades at
+type MatchingAlgorithm string
+
+const (
+	// Price-time priority, the oldest order first
+	MatchingAlgorithmFifo MatchingAlgorithm = "FIFO"
+	// In proportion to the visible size of the orders
+	MatchingAlgorithmProRata MatchingAlgorithm = "PRO_RATA"
+	// The oldest order first up to its visible size, then pro-rata
+	MatchingAlgorithmProRataTopFifo MatchingAlgorithm = "PRO_RATA_TOP_FIFO"
+)
+
+func NewMatchingAlgorithmFromString(s string) (*MatchingAlgorithm, error) {
+	algorithm := MatchingAlgorithm(s)
+	switch algorithm {
+	case MatchingAlgorithmFifo:
+	case MatchingAlgorithmProRata:
+	case MatchingAlgorithmProRataTopFifo:
+	default:
+		return nil, fmt.Errorf("invalid matching algorithm: %v", s)
+	}
+	return &algorithm, nil
+}
+
+// IsProRata reports whether orders at a price share the trades in proportion
+// to their size
+func (a MatchingAlgorithm) IsProRata() bool {
+	return a == MatchingAlgorithmProRata || a == MatchingAlgorithmProRataTopFifo
+}
@@ -14,11 +14,12 @@ import (
 	"github.com/siddontang/go-log/log"
 )
 
+// The topics the publisher and the workers listen to
 const (
-	TopicOrder   = "orders"
-	TopicAccount = "accounts"
-	TopicFill    = "fills"
-	TopicBill    = "bills"
+	TopicOrder   = entities.TopicOrder
+	TopicAccount = entities.TopicAccount
+	TopicFill    = entities.TopicFill
+	TopicBill    = entities.TopicBill
 )
 
 type BinLogStream struct {
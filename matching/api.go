@@ -1,13 +1,15 @@
 package matching
 
-import (
-	"github.com/irononet/go-exchange/entities"
-)
-
 type OrderReader interface {
 	SetOffset(offset int64) error
 
-	FetchOrder() (offset int64, order *entities.Order, err error)
+	FetchCommand() (offset int64, command *Command, err error)
+
+	Close() error
+}
+
+type CommandWriter interface {
+	Write(commands ...*Command) error
 }
 
 type LogStore interface {
@@ -19,14 +21,29 @@ type LogReader interface {
 	RegisterObserver(observer LogObserver)
 
 	Run(seq, offset int64)
+
+	// Stop makes Run return, the reader can't be run again
+	Stop()
 }
 
 type LogObserver interface {
+	OnReceivedLog(log *ReceivedLog, offset int64)
+
 	OnOpenLOg(log *OpenLog, offset int64)
 
 	OnMatchLog(log *MatchLog, offset int64)
 
 	OnDoneLog(log *DoneLog, offset int64)
+
+	OnActivateLog(log *ActivateLog, offset int64)
+
+	OnChangeLog(log *ChangeLog, offset int64)
+
+	OnAmendLog(log *AmendLog, offset int64)
+
+	OnStatusLog(log *StatusLog, offset int64)
+
+	OnRejectedLog(log *RejectedLog, offset int64)
 }
 
 type SnapshotStore interface {
@@ -2,28 +2,42 @@ package matching
 
 import (
 	"strconv"
+	"time"
 
 	"github.com/irononet/go-exchange/conf"
+	"github.com/irononet/go-exchange/entities"
 	"github.com/irononet/go-exchange/service"
 	"github.com/siddontang/go-log/log"
 )
 
+// how often the products are checked for listings and delistings
+const productWatchInterval = 10 * time.Second
+
 func StartEngine() {
 	gexConfig := conf.GetConfig()
 
-	products, err := service.GetProducts()
-	if err != nil {
-		panic(err)
-	}
+	engines := map[string]*Engine{}
 
-	for _, product := range products {
+	service.WatchProducts(productWatchInterval, func(product *entities.Product) {
 		productId := strconv.Itoa(int(product.ID))
 		orderReader := NewKafkaOrderReader(productId, gexConfig.Kafka.Brokers)
 		snapshotStore := NewRedisSnapShotStore(productId)
+		commandWriter := NewKafkaCommandWriter(productId, gexConfig.Kafka.Brokers)
 		logStore := NewKafkaLogStore(productId, gexConfig.Kafka.Brokers)
-		matchEngine := NewEngine(product, orderReader, logStore, snapshotStore)
+		matchEngine := NewEngine(product, orderReader, commandWriter, logStore, snapshotStore)
 		matchEngine.Start()
-	}
+		engines[productId] = matchEngine
+
+		log.Infof("match engine of %v started", productId)
+	}, func(product *entities.Product) {
+		productId := strconv.Itoa(int(product.ID))
+		if matchEngine, found := engines[productId]; found {
+			matchEngine.Stop()
+			delete(engines, productId)
+		}
+
+		log.Infof("match engine of %v stopped", productId)
+	})
 
 	log.Info("match engine ok")
 }
@@ -0,0 +1,216 @@
+package matching
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/irononet/go-exchange/entities"
+)
+
+// CommandVersion is the schema version of the commands written to the order
+// topic. The raw orders written before the envelope existed have no version.
+const CommandVersion = 1
+
+type CommandType string
+
+const (
+	CommandTypeNewOrder       = CommandType("newOrder")
+	CommandTypeCancel         = CommandType("cancel")
+	CommandTypeAmend          = CommandType("amend")
+	CommandTypeMassCancel     = CommandType("massCancel")
+	CommandTypeProductControl = CommandType("productControl")
+	CommandTypeNewOrderGroup  = CommandType("newOrderGroup")
+	CommandTypeMassQuote      = CommandType("massQuote")
+	CommandTypeTick           = CommandType("tick")
+)
+
+// Command is the envelope of every message on the order topic of a product
+type Command struct {
+	Version int         `json:"version"`
+	Type    CommandType `json:"type"`
+
+	// Order of a newOrder, cancel or amend command. The size and price of an
+	// amend are the new ones
+	Order *entities.Order `json:"order,omitempty"`
+
+	MassCancel *MassCancelCommand `json:"massCancel,omitempty"`
+
+	ProductControl *ProductControlCommand `json:"productControl,omitempty"`
+
+	OrderGroup *OrderGroupCommand `json:"orderGroup,omitempty"`
+
+	MassQuote *MassQuoteCommand `json:"massQuote,omitempty"`
+
+	Tick *TickCommand `json:"tick,omitempty"`
+}
+
+// MassCancelCommand cancels every order of a user on the product
+type MassCancelCommand struct {
+	UserId int64 `json:"userId"`
+
+	// Only cancel the orders of this side, both sides if nil
+	Side *entities.Side `json:"side,omitempty"`
+}
+
+// MassQuoteCommand replaces every quote of a user on the product with new ones
+type MassQuoteCommand struct {
+	UserId int64             `json:"userId"`
+	Orders []*entities.Order `json:"orders"`
+}
+
+// TickCommand advances the clock of the book, which expires the orders whose
+// time has passed. The time is written with the command, so that a replay of
+// the topic expires them at the same point.
+type TickCommand struct {
+	Time time.Time `json:"time"`
+}
+
+// OrderGroupCommand places the orders of a group in a single step
+type OrderGroupCommand struct {
+	GroupId int64                   `json:"groupId"`
+	Type    entities.OrderGroupType `json:"type"`
+	Orders  []*entities.Order       `json:"orders"`
+}
+
+// ProductControlCommand changes how the engine of the product trades
+type ProductControlCommand struct {
+	// Trading status the product switches to, unchanged if empty
+	Status string `json:"status"`
+
+	// Time an auction uncrosses and switches to open on the clock of the
+	// book, which the ticks advance. The auction lasts until the next status
+	// if nil
+	AuctionEnd *time.Time `json:"auctionEnd,omitempty"`
+
+	// Price band and circuit breaker of the product, unchanged if nil
+	PriceLimits *entities.PriceLimits `json:"priceLimits,omitempty"`
+
+	// How trades are shared between the orders at a price, unchanged if
+	// empty
+	MatchingAlgorithm entities.MatchingAlgorithm `json:"matchingAlgorithm,omitempty"`
+}
+
+func NewOrderCommand(order *entities.Order) *Command {
+	return &Command{Version: CommandVersion, Type: CommandTypeNewOrder, Order: order}
+}
+
+func NewCancelCommand(order *entities.Order) *Command {
+	return &Command{Version: CommandVersion, Type: CommandTypeCancel, Order: order}
+}
+
+func NewAmendCommand(order *entities.Order) *Command {
+	return &Command{Version: CommandVersion, Type: CommandTypeAmend, Order: order}
+}
+
+func NewMassCancelCommand(userId int64, side *entities.Side) *Command {
+	return &Command{
+		Version:    CommandVersion,
+		Type:       CommandTypeMassCancel,
+		MassCancel: &MassCancelCommand{UserId: userId, Side: side},
+	}
+}
+
+func NewProductControlCommand(status string, auctionEnd *time.Time) *Command {
+	return &Command{
+		Version:        CommandVersion,
+		Type:           CommandTypeProductControl,
+		ProductControl: &ProductControlCommand{Status: status, AuctionEnd: auctionEnd},
+	}
+}
+
+func NewMassQuoteCommand(userId int64, orders []*entities.Order) *Command {
+	return &Command{
+		Version:   CommandVersion,
+		Type:      CommandTypeMassQuote,
+		MassQuote: &MassQuoteCommand{UserId: userId, Orders: orders},
+	}
+}
+
+func NewOrderGroupCommand(group *entities.OrderGroup, orders []*entities.Order) *Command {
+	return &Command{
+		Version:    CommandVersion,
+		Type:       CommandTypeNewOrderGroup,
+		OrderGroup: &OrderGroupCommand{GroupId: int64(group.ID), Type: group.Type, Orders: orders},
+	}
+}
+
+func NewTickCommand(now time.Time) *Command {
+	return &Command{Version: CommandVersion, Type: CommandTypeTick, Tick: &TickCommand{Time: now}}
+}
+
+// NewProductSettingsCommand applies the price limits and the matching algorithm
+// of the product
+func NewProductSettingsCommand(product *entities.Product) *Command {
+	limits := product.PriceLimits
+	return &Command{
+		Version: CommandVersion,
+		Type:    CommandTypeProductControl,
+		ProductControl: &ProductControlCommand{
+			PriceLimits:       &limits,
+			MatchingAlgorithm: product.GetMatchingAlgorithm(),
+		},
+	}
+}
+
+// decodeCommand decodes a message of the order topic. A message without version
+// is a raw order, whose status tells a cancel or an amend from a new order.
+func decodeCommand(buf []byte) (*Command, error) {
+	var command Command
+	err := json.Unmarshal(buf, &command)
+	if err != nil {
+		return nil, err
+	}
+
+	if command.Version == 0 {
+		var order entities.Order
+		err = json.Unmarshal(buf, &order)
+		if err != nil {
+			return nil, err
+		}
+
+		switch order.Status {
+		case entities.OrderStatusCancelling:
+			return NewCancelCommand(&order), nil
+		case entities.OrderStatusAmending:
+			return NewAmendCommand(&order), nil
+		default:
+			return NewOrderCommand(&order), nil
+		}
+	}
+
+	if command.Version > CommandVersion {
+		return nil, fmt.Errorf("unsupported command version: %v", command.Version)
+	}
+
+	switch command.Type {
+	case CommandTypeNewOrder, CommandTypeCancel, CommandTypeAmend:
+		if command.Order == nil {
+			return nil, fmt.Errorf("%v command without order", command.Type)
+		}
+	case CommandTypeMassCancel:
+		if command.MassCancel == nil {
+			return nil, errors.New("massCancel command without parameters")
+		}
+	case CommandTypeProductControl:
+		if command.ProductControl == nil {
+			return nil, errors.New("productControl command without parameters")
+		}
+	case CommandTypeMassQuote:
+		if command.MassQuote == nil {
+			return nil, errors.New("massQuote command without parameters")
+		}
+	case CommandTypeNewOrderGroup:
+		if command.OrderGroup == nil || len(command.OrderGroup.Orders) == 0 {
+			return nil, errors.New("newOrderGroup command without orders")
+		}
+	case CommandTypeTick:
+		if command.Tick == nil {
+			return nil, errors.New("tick command without time")
+		}
+	default:
+		return nil, fmt.Errorf("unknown command type: %v", command.Type)
+	}
+	return &command, nil
+}
@@ -8,6 +8,9 @@ import (
 	logger "github.com/siddontang/go-log/log"
 )
 
+// how often the engine writes a tick to its order topic
+const tickInterval = time.Second
+
 type Engine struct {
 
 	// productID
@@ -19,7 +22,10 @@ type Engine struct {
 
 	OrderOffset int64
 
-	OrderCh chan *OffsetOrder
+	// Writes the ticks to the order topic
+	CommandWriter CommandWriter
+
+	CommandCh chan *OffsetCommand
 
 	LogStore LogStore
 
@@ -32,6 +38,9 @@ type Engine struct {
 	SnapshotCh chan *Snapshot
 
 	SnapShotStore SnapshotStore
+
+	// closed to stop the engine
+	stopCh chan struct{}
 }
 
 type Snapshot struct {
@@ -39,23 +48,26 @@ type Snapshot struct {
 	OrderOffset       int64
 }
 
-type OffsetOrder struct {
-	Offset int64
-	Order  *entities.Order
+type OffsetCommand struct {
+	Offset  int64
+	Command *Command
 }
 
-func NewEngine(product *entities.Product, orderReader OrderReader, logStore LogStore, snapshotStore SnapshotStore) *Engine {
+func NewEngine(product *entities.Product, orderReader OrderReader, commandWriter CommandWriter, logStore LogStore,
+	snapshotStore SnapshotStore) *Engine {
 	e := &Engine{
 		productId:            strconv.Itoa(int(product.ID)),
 		OrderBook:            NewOrderBook(product),
 		LogCh:                make(chan Log, 10000),
-		OrderCh:              make(chan *OffsetOrder, 10000),
+		CommandCh:            make(chan *OffsetCommand, 10000),
 		SnapshotReqCh:        make(chan *Snapshot, 32),
 		SnapshotApproveReqCh: make(chan *Snapshot, 32),
 		SnapshotCh:           make(chan *Snapshot, 32),
 		SnapShotStore:        snapshotStore,
 		OrderReader:          orderReader,
+		CommandWriter:        commandWriter,
 		LogStore:             logStore,
+		stopCh:               make(chan struct{}),
 	}
 
 	snapshot, err := snapshotStore.GetLatest()
@@ -74,6 +86,17 @@ func (e *Engine) Start() {
 	go e.runApplier()
 	go e.runCommitter()
 	go e.runShapshots()
+	go e.runTicker()
+}
+
+// Stop stops fetching and applying commands. The logs already made are still
+// stored, the engine can't be started again.
+func (e *Engine) Stop() {
+	close(e.stopCh)
+	err := e.OrderReader.Close()
+	if err != nil {
+		logger.Error(err)
+	}
 }
 
 func (e *Engine) runFetcher() {
@@ -87,12 +110,42 @@ func (e *Engine) runFetcher() {
 	}
 
 	for {
-		offset, order, err := e.OrderReader.FetchOrder()
+		offset, command, err := e.OrderReader.FetchCommand()
 		if err != nil {
+			select {
+			case <-e.stopCh:
+				return
+			default:
+			}
 			logger.Error(err)
 			continue
 		}
-		e.OrderCh <- &OffsetOrder{offset, order}
+
+		select {
+		case e.CommandCh <- &OffsetCommand{offset, command}:
+		case <-e.stopCh:
+			return
+		}
+	}
+}
+
+// runTicker writes a tick to the order topic every tickInterval, the book
+// applies it in order with the other commands
+func (e *Engine) runTicker() {
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.stopCh:
+			return
+
+		case now := <-ticker.C:
+			err := e.CommandWriter.Write(NewTickCommand(now))
+			if err != nil {
+				logger.Error(err)
+			}
+		}
 	}
 }
 
@@ -101,21 +154,20 @@ func (e *Engine) runApplier() {
 
 	for {
 		select {
-		case offsetOrder := <-e.OrderCh:
-			var logs []Log
-			if offsetOrder.Order.Status == entities.OrderStatusCancelling {
-				logs = e.OrderBook.CancelOrder(offsetOrder.Order)
-			} else {
-				logs = e.OrderBook.ApplyOrder(offsetOrder.Order)
-			}
+		case <-e.stopCh:
+			logger.Infof("%v engine stopped at order offset %v", e.productId, orderOffset)
+			return
+
+		case offsetCommand := <-e.CommandCh:
+			logs := e.applyCommand(offsetCommand.Command)
 
 			for _, log := range logs {
 				e.LogCh <- log
 			}
 
-			orderOffset = offsetOrder.Offset
+			orderOffset = offsetCommand.Offset
 
-		case snapshot := <-e.SnapshotCh:
+		case snapshot := <-e.SnapshotReqCh:
 			delta := orderOffset - snapshot.OrderOffset
 			if delta <= 1000 {
 				continue
@@ -130,6 +182,51 @@ func (e *Engine) runApplier() {
 	}
 }
 
+// applyCommand applies a command to the book, and then reprices the pegged
+// orders if the command moved the best prices
+func (e *Engine) applyCommand(command *Command) []Log {
+	logs := e.applyCommandToBook(command)
+	return append(logs, e.OrderBook.RepricePegs()...)
+}
+
+func (e *Engine) applyCommandToBook(command *Command) []Log {
+	switch command.Type {
+	case CommandTypeNewOrder:
+		return e.OrderBook.ApplyOrder(command.Order)
+	case CommandTypeCancel:
+		return e.OrderBook.CancelOrder(command.Order)
+	case CommandTypeAmend:
+		return e.OrderBook.AmendOrder(command.Order)
+	case CommandTypeMassQuote:
+		return e.OrderBook.MassQuote(command.MassQuote.UserId, command.MassQuote.Orders)
+	case CommandTypeNewOrderGroup:
+		group := command.OrderGroup
+		return e.OrderBook.ApplyOrderGroup(group.GroupId, group.Type, group.Orders)
+	case CommandTypeMassCancel:
+		return e.OrderBook.MassCancel(command.MassCancel.UserId, command.MassCancel.Side)
+	case CommandTypeTick:
+		return e.OrderBook.Tick(command.Tick.Time)
+	case CommandTypeProductControl:
+		if command.ProductControl.PriceLimits != nil {
+			e.OrderBook.SetPriceLimits(*command.ProductControl.PriceLimits)
+		}
+		if len(command.ProductControl.MatchingAlgorithm) != 0 {
+			e.OrderBook.SetMatchingAlgorithm(command.ProductControl.MatchingAlgorithm)
+		}
+		if len(command.ProductControl.Status) == 0 {
+			return nil
+		}
+		var auctionEnd time.Time
+		if command.ProductControl.AuctionEnd != nil {
+			auctionEnd = *command.ProductControl.AuctionEnd
+		}
+		return e.OrderBook.SetTradingStatus(entities.TradingStatus(command.ProductControl.Status), auctionEnd)
+	default:
+		logger.Warnf("%v unsupported command: %v", e.productId, command.Type)
+		return nil
+	}
+}
+
 func (e *Engine) runCommitter() {
 	var seq = e.OrderBook.LogSeq
 	var pending *Snapshot = nil
@@ -137,6 +234,23 @@ func (e *Engine) runCommitter() {
 
 	for {
 		select {
+		case <-e.stopCh:
+			// store what the applier made before it stopped
+			for len(e.LogCh) > 0 {
+				log := <-e.LogCh
+				if log.GetSeq() > seq {
+					seq = log.GetSeq()
+					logs = append(logs, log)
+				}
+			}
+			if len(logs) > 0 {
+				err := e.LogStore.Store(logs)
+				if err != nil {
+					logger.Error(err)
+				}
+			}
+			return
+
 		case log := <-e.LogCh:
 			if log.GetSeq() <= seq {
 				logger.Infof("discard log seq=%v", seq)
@@ -186,10 +300,15 @@ func (e *Engine) runShapshots() {
 
 	for {
 		select {
+		case <-e.stopCh:
+			return
+
 		case <-time.After(30 * time.Second):
 			// make a new snapshot request
-			e.SnapshotReqCh <- &Snapshot{
-				OrderOffset: orderOffset,
+			select {
+			case e.SnapshotReqCh <- &Snapshot{OrderOffset: orderOffset}:
+			case <-e.stopCh:
+				return
 			}
 		case snapshot := <-e.SnapshotCh:
 			// store snapshot
@@ -0,0 +1,67 @@
+package matching
+
+import (
+	"time"
+
+	"github.com/emirpasic/gods/maps/treemap"
+	"github.com/irononet/go-exchange/entities"
+)
+
+// expiryQueue orders the GTT orders by their expire time. Entries are removed
+// lazily: an order which left the book before its expiry stays in the queue and
+// is skipped once popped.
+type expiryQueue struct {
+	// expireTimeOrderIdKey - side
+	queue *treemap.Map
+}
+
+type expireTimeOrderIdKey struct {
+	expireTime time.Time
+	orderId    int64
+}
+
+func newExpiryQueue() *expiryQueue {
+	return &expiryQueue{
+		queue: treemap.NewWith(expireTimeOrderIdKeyComparator),
+	}
+}
+
+func (q *expiryQueue) add(order *BookOrder) {
+	q.queue.Put(&expireTimeOrderIdKey{order.ExpireTime, order.OrderId}, order.Side)
+}
+
+// popExpired removes and returns the first entry which expires at or before now
+func (q *expiryQueue) popExpired(now time.Time) (orderId int64, side entities.Side, found bool) {
+	key, value := q.queue.Min()
+	if key == nil {
+		return 0, "", false
+	}
+
+	expiryKey := key.(*expireTimeOrderIdKey)
+	if expiryKey.expireTime.After(now) {
+		return 0, "", false
+	}
+
+	q.queue.Remove(key)
+	return expiryKey.orderId, value.(entities.Side), true
+}
+
+func expireTimeOrderIdKeyComparator(a, b interface{}) int {
+	aAsserted := a.(*expireTimeOrderIdKey)
+	bAsserted := b.(*expireTimeOrderIdKey)
+
+	if aAsserted.expireTime.Before(bAsserted.expireTime) {
+		return -1
+	} else if aAsserted.expireTime.After(bAsserted.expireTime) {
+		return 1
+	}
+
+	y := aAsserted.orderId - bAsserted.orderId
+	if y == 0 {
+		return 0
+	} else if y > 0 {
+		return 1
+	} else {
+		return -1
+	}
+}
@@ -0,0 +1,59 @@
+package matching
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/irononet/go-exchange/conf"
+	"github.com/segmentio/kafka-go"
+)
+
+var productId2CommandWriter sync.Map
+
+type KafkaCommandWriter struct {
+	commandWriter *kafka.Writer
+}
+
+func NewKafkaCommandWriter(productId string, brokers []string) *KafkaCommandWriter {
+	s := &KafkaCommandWriter{}
+
+	s.commandWriter = kafka.NewWriter(kafka.WriterConfig{
+		Brokers:      brokers,
+		Topic:        TopicOrderPrefix + productId,
+		Balancer:     &kafka.LeastBytes{},
+		BatchTimeout: 5 * time.Millisecond,
+	})
+
+	return s
+}
+
+func (s *KafkaCommandWriter) Write(commands ...*Command) error {
+	var messages []kafka.Message
+	for _, command := range commands {
+		val, err := json.Marshal(command)
+		if err != nil {
+			return err
+		}
+
+		messages = append(messages, kafka.Message{Value: val})
+	}
+
+	return s.commandWriter.WriteMessages(context.Background(), messages...)
+}
+
+// SharedCommandWriter returns the writer of the order topic of the product,
+// shared by everyone who submits commands to its engine
+func SharedCommandWriter(productId string) *KafkaCommandWriter {
+	writer, found := productId2CommandWriter.Load(productId)
+	if found {
+		return writer.(*KafkaCommandWriter)
+	}
+
+	gexConfig := conf.GetConfig()
+
+	newWriter := NewKafkaCommandWriter(productId, gexConfig.Kafka.Brokers)
+	writer, _ = productId2CommandWriter.LoadOrStore(productId, newWriter)
+	return writer.(*KafkaCommandWriter)
+}
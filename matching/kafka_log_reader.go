@@ -16,6 +16,7 @@ type KafkaLogReader struct {
 	productId string
 	reader    *kafka.Reader
 	observer  LogObserver
+	stopCh    chan struct{}
 }
 
 func NewKafkaLogReader(readerId string, productId string, brokers []string) LogReader {
@@ -26,7 +27,7 @@ func NewKafkaLogReader(readerId string, productId string, brokers []string) LogR
 		MinBytes:  1,
 		MaxBytes:  10e6,
 	})
-	return &KafkaLogReader{readerId: readerId, productId: productId, reader: reader}
+	return &KafkaLogReader{readerId: readerId, productId: productId, reader: reader, stopCh: make(chan struct{})}
 }
 
 func (r *KafkaLogReader) GetProductId() string {
@@ -37,6 +38,11 @@ func (r *KafkaLogReader) RegisterObserver(observer LogObserver) {
 	r.observer = observer
 }
 
+func (r *KafkaLogReader) Stop() {
+	close(r.stopCh)
+	_ = r.reader.Close()
+}
+
 func (r *KafkaLogReader) Run(seq, offset int64) {
 	logger.Infof("%v:%v read from %v", r.productId, r.readerId, offset)
 
@@ -50,7 +56,13 @@ func (r *KafkaLogReader) Run(seq, offset int64) {
 	for {
 		kMessage, err := r.reader.FetchMessage(context.Background())
 		if err != nil {
-			logger.Error()
+			select {
+			case <-r.stopCh:
+				logger.Infof("%v:%v stopped", r.productId, r.readerId)
+				return
+			default:
+			}
+			logger.Error(err)
 			continue
 		}
 
@@ -69,6 +81,14 @@ func (r *KafkaLogReader) Run(seq, offset int64) {
 		lastSeq = base.Sequence
 
 		switch base.Type {
+		case LogTypeReceived:
+			var log ReceivedLog
+			err := json.Unmarshal(kMessage.Value, &log)
+			if err != nil {
+				panic(err)
+			}
+			r.observer.OnReceivedLog(&log, kMessage.Offset)
+
 		case LogTypeOpen:
 			var log OpenLog
 			err := json.Unmarshal(kMessage.Value, &log)
@@ -92,6 +112,46 @@ func (r *KafkaLogReader) Run(seq, offset int64) {
 				panic(err)
 			}
 			r.observer.OnDoneLog(&log, kMessage.Offset)
+
+		case LogTypeActivate:
+			var log ActivateLog
+			err := json.Unmarshal(kMessage.Value, &log)
+			if err != nil {
+				panic(err)
+			}
+			r.observer.OnActivateLog(&log, kMessage.Offset)
+
+		case LogTypeChange:
+			var log ChangeLog
+			err := json.Unmarshal(kMessage.Value, &log)
+			if err != nil {
+				panic(err)
+			}
+			r.observer.OnChangeLog(&log, kMessage.Offset)
+
+		case LogTypeAmend:
+			var log AmendLog
+			err := json.Unmarshal(kMessage.Value, &log)
+			if err != nil {
+				panic(err)
+			}
+			r.observer.OnAmendLog(&log, kMessage.Offset)
+
+		case LogTypeStatus:
+			var log StatusLog
+			err := json.Unmarshal(kMessage.Value, &log)
+			if err != nil {
+				panic(err)
+			}
+			r.observer.OnStatusLog(&log, kMessage.Offset)
+
+		case LogTypeRejected:
+			var log RejectedLog
+			err := json.Unmarshal(kMessage.Value, &log)
+			if err != nil {
+				panic(err)
+			}
+			r.observer.OnRejectedLog(&log, kMessage.Offset)
 		}
 	}
 }
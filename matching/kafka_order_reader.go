@@ -2,8 +2,6 @@ package matching
 
 import (
 	"context"
-	"encoding/json"
-	"github.com/irononet/go-exchange/entities"
 	"github.com/segmentio/kafka-go"
 )
 
@@ -33,16 +31,20 @@ func (s *KafkaOrderReader) SetOffset(offset int64) error {
 	return s.OrderReader.SetOffset(offset)
 }
 
-func (s *KafkaOrderReader) FetchOrder() (offset int64, order *entities.Order, err error) {
+func (s *KafkaOrderReader) Close() error {
+	return s.OrderReader.Close()
+}
+
+func (s *KafkaOrderReader) FetchCommand() (offset int64, command *Command, err error) {
 	message, err := s.OrderReader.FetchMessage(context.Background())
 	if err != nil {
 		return 0, nil, err
 	}
 
-	err = json.Unmarshal(message.Value, &order)
+	command, err = decodeCommand(message.Value)
 	if err != nil {
 		return 0, nil, err
 	}
 
-	return message.Offset, order, nil
+	return message.Offset, command, nil
 }
@@ -9,9 +9,16 @@ import (
 type LogType string
 
 const (
-	LogTypeMatch = LogType("match")
-	LogTypeOpen  = LogType("open")
-	LogTypeDone  = LogType("done")
+	LogTypeReceived = LogType("received")
+	LogTypeMatch    = LogType("match")
+	LogTypeOpen     = LogType("open")
+	LogTypeDone     = LogType("done")
+
+	LogTypeActivate = LogType("activate")
+	LogTypeChange   = LogType("change")
+	LogTypeAmend    = LogType("amend")
+	LogTypeStatus   = LogType("status")
+	LogTypeRejected = LogType("rejected")
 )
 
 type Log interface {
@@ -27,11 +34,30 @@ type Base struct {
 
 type ReceivedLog struct {
 	Base
-	OrderId   int64
-	Size      decimal.Decimal
-	Price     decimal.Decimal
-	Side      entities.Side
-	OrderType entities.OrderType
+	OrderId     int64
+	Size        decimal.Decimal
+	Funds       decimal.Decimal
+	Price       decimal.Decimal
+	StopPrice   decimal.Decimal
+	Side        entities.Side
+	OrderType   entities.OrderType
+	DisplaySize decimal.Decimal
+	Hidden      bool
+}
+
+func newReceivedLog(logSeq int64, productId int64, order *BookOrder) *ReceivedLog {
+	return &ReceivedLog{
+		Base:        Base{LogTypeReceived, logSeq, productId, time.Now()},
+		OrderId:     order.OrderId,
+		Size:        order.Size,
+		Funds:       order.Funds,
+		Price:       order.Price,
+		StopPrice:   order.StopPrice,
+		Side:        order.Side,
+		OrderType:   order.Type,
+		DisplaySize: order.DisplaySize,
+		Hidden:      order.Hidden,
+	}
 }
 
 func (l *ReceivedLog) GetSeq() int64 {
@@ -40,7 +66,10 @@ func (l *ReceivedLog) GetSeq() int64 {
 
 type OpenLog struct {
 	Base
-	OrderId       int64
+	OrderId int64
+
+	// Size shown on the book, without the reserve of an iceberg order and
+	// zero for a hidden order
 	RemainingSize decimal.Decimal
 	Price         decimal.Decimal
 	Side          entities.Side
@@ -50,7 +79,7 @@ func newOpenLog(logSeq int64, productId int64, takerOrder *BookOrder) *OpenLog {
 	return &OpenLog{
 		Base:          Base{LogTypeOpen, logSeq, productId, time.Now()},
 		OrderId:       takerOrder.OrderId,
-		RemainingSize: takerOrder.Size,
+		RemainingSize: takerOrder.displayedSize(),
 		Price:         takerOrder.Price,
 		Side:          takerOrder.Side,
 	}
@@ -67,6 +96,7 @@ type DoneLog struct {
 	RemainingSize decimal.Decimal
 	Reason        entities.DoneReason
 	Side          entities.Side
+	Hidden        bool
 }
 
 func newDoneLog(logSeq int64, productId int64, order *BookOrder, remainingSize decimal.Decimal, reason entities.DoneReason) *DoneLog {
@@ -77,6 +107,7 @@ func newDoneLog(logSeq int64, productId int64, order *BookOrder, remainingSize d
 		RemainingSize: remainingSize,
 		Reason:        reason,
 		Side:          order.Side,
+		Hidden:        order.Hidden,
 	}
 }
 
@@ -109,3 +140,148 @@ func newMatchLog(logSeq int64, productId int64, tradeSeq int64, takerOrder, make
 func (l *MatchLog) GetSeq() int64 {
 	return l.Sequence
 }
+
+type ActivateLog struct {
+	Base
+	OrderId   int64
+	StopPrice decimal.Decimal
+	Price     decimal.Decimal
+	Size      decimal.Decimal
+	Funds     decimal.Decimal
+	Side      entities.Side
+	OrderType entities.OrderType
+	Hidden    bool
+}
+
+func newActivateLog(logSeq int64, productId int64, stopOrder *BookOrder) *ActivateLog {
+	return &ActivateLog{
+		Base:      Base{LogTypeActivate, logSeq, productId, time.Now()},
+		OrderId:   stopOrder.OrderId,
+		StopPrice: stopOrder.StopPrice,
+		Price:     stopOrder.Price,
+		Size:      stopOrder.Size,
+		Funds:     stopOrder.Funds,
+		Side:      stopOrder.Side,
+		OrderType: stopOrder.Type,
+		Hidden:    stopOrder.Hidden,
+	}
+}
+
+func (l *ActivateLog) GetSeq() int64 {
+	return l.Sequence
+}
+
+type ChangeLog struct {
+	Base
+	OrderId int64
+
+	// Sizes shown on the book, like the RemainingSize of the open log
+	NewSize decimal.Decimal
+	OldSize decimal.Decimal
+	Price   decimal.Decimal
+
+	// Price a repriced pegged order moved to, the same as Price otherwise
+	NewPrice decimal.Decimal
+	Side     entities.Side
+}
+
+func newChangeLog(logSeq int64, productId int64, order *BookOrder, oldSize, newSize decimal.Decimal) *ChangeLog {
+	return &ChangeLog{
+		Base:     Base{LogTypeChange, logSeq, productId, time.Now()},
+		OrderId:  order.OrderId,
+		NewSize:  newSize,
+		OldSize:  oldSize,
+		Price:    order.Price,
+		NewPrice: order.Price,
+		Side:     order.Side,
+	}
+}
+
+func (l *ChangeLog) GetSeq() int64 {
+	return l.Sequence
+}
+
+type AmendLog struct {
+	Base
+	OrderId int64
+
+	// New size of the order, including what is already filled
+	Size     decimal.Decimal
+	Price    decimal.Decimal
+	OldPrice decimal.Decimal
+
+	// Size shown on the book after an amend in place
+	RemainingSize decimal.Decimal
+
+	// The order lost its time priority. It was taken off the book and is
+	// matched again at the new price, so the logs which follow show where it
+	// ends up
+	Requeued bool
+	Side     entities.Side
+}
+
+func newAmendLog(logSeq int64, productId int64, order *BookOrder, oldPrice decimal.Decimal, requeued bool) *AmendLog {
+	amendLog := &AmendLog{
+		Base:     Base{LogTypeAmend, logSeq, productId, time.Now()},
+		OrderId:  order.OrderId,
+		Size:     order.TotalSize,
+		Price:    order.Price,
+		OldPrice: oldPrice,
+		Requeued: requeued,
+		Side:     order.Side,
+	}
+	if !requeued {
+		amendLog.RemainingSize = order.displayedSize()
+	}
+	return amendLog
+}
+
+func (l *AmendLog) GetSeq() int64 {
+	return l.Sequence
+}
+
+type StatusLog struct {
+	Base
+	Status    entities.TradingStatus
+	OldStatus entities.TradingStatus
+	Reason    entities.StatusReason
+}
+
+func newStatusLog(logSeq int64, productId int64, status, oldStatus entities.TradingStatus, reason entities.StatusReason) *StatusLog {
+	return &StatusLog{
+		Base:      Base{LogTypeStatus, logSeq, productId, time.Now()},
+		Status:    status,
+		OldStatus: oldStatus,
+		Reason:    reason,
+	}
+}
+
+func (l *StatusLog) GetSeq() int64 {
+	return l.Sequence
+}
+
+// RejectedLog turns away an order the engine never took, which is settled as
+// if it had never been placed, or an amend of an order, which leaves the order
+// as it was
+type RejectedLog struct {
+	Base
+	OrderId int64
+	UserId  int64
+	Side    entities.Side
+	Reason  entities.RejectReason
+	Amend   bool
+}
+
+func newRejectedLog(logSeq int64, productId int64, order *BookOrder, reason entities.RejectReason) *RejectedLog {
+	return &RejectedLog{
+		Base:    Base{LogTypeRejected, logSeq, productId, time.Now()},
+		OrderId: order.OrderId,
+		UserId:  order.UserId,
+		Side:    order.Side,
+		Reason:  reason,
+	}
+}
+
+func (l *RejectedLog) GetSeq() int64 {
+	return l.Sequence
+}
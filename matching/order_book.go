@@ -5,6 +5,8 @@ import (
 	"fmt"
 
 	"math"
+	"sort"
+	"time"
 
 	"github.com/emirpasic/gods/maps/treemap"
 	"github.com/irononet/go-exchange/entities"
@@ -22,6 +24,17 @@ type OrderBook struct {
 	// bids & asks depth
 	depths map[entities.Side]*depth
 
+	// untriggered buy & sell stop orders
+	stopBooks map[entities.Side]*stopBook
+
+	// GTT orders ordered by expire time
+	expiries *expiryQueue
+
+	// Latest time of the ticks and the orders applied. Both carry their own
+	// time, so expiring GTT orders and ending auctions stays deterministic on
+	// replay
+	clock time.Time
+
 	// Stricly continuously increasing transaction ID, used for the primary key
 	// of the trade
 	tradeSeq int64
@@ -32,6 +45,49 @@ type OrderBook struct {
 	// Deduplication measure to prevent the order from repeatedly being
 	// submitted to the order book
 	orderIdWindow Window
+
+	// Client uuids of the recent orders per user, an order reusing one is
+	// rejected
+	clientUuidWindow clientUuidWindow
+
+	// What the book accepts, changed by product control commands
+	tradingStatus entities.TradingStatus
+
+	// Price band and circuit breaker, from the product and then from product
+	// control commands
+	priceLimits entities.PriceLimits
+
+	// Trades within the window of the circuit breaker, after the last trade
+	// before the window which the moves are measured from
+	recentTrades []tradePrice
+
+	// Time the auction in progress uncrosses on the clock of the book, zero
+	// if the auction lasts until the trading status changes
+	auctionEnd time.Time
+
+	// Trading status the book switches to once the auction uncrosses at its
+	// end time
+	resumeStatus entities.TradingStatus
+
+	// How trades are shared between the orders at a price, from the product
+	// and then from product control commands
+	matchingAlgorithm entities.MatchingAlgorithm
+
+	// Side of every pegged order which may still be on the book
+	pegs map[int64]entities.Side
+
+	// Best prices the pegged orders were last priced from
+	pegBestBid decimal.Decimal
+	pegBestAsk decimal.Decimal
+
+	// Order groups with legs still alive, and the group of every such leg
+	groups    map[int64]*orderGroup
+	legGroups map[int64]int64
+}
+
+type tradePrice struct {
+	Time  time.Time
+	Price decimal.Decimal
 }
 
 type orderBooSnapShot struct {
@@ -41,6 +97,9 @@ type orderBooSnapShot struct {
 	// All orders
 	Orders []BookOrder
 
+	// All untriggered stop orders
+	StopOrders []BookOrder
+
 	// Trade seq at snapshot time
 	TradeSeq int64
 
@@ -49,11 +108,31 @@ type orderBooSnapShot struct {
 
 	// State of the duplication window
 	OrderIdWindow Window
+
+	// State of the client uuid duplication window
+	ClientUuidWindow clientUuidWindow
+
+	TradingStatus entities.TradingStatus
+
+	PriceLimits  *entities.PriceLimits
+	RecentTrades []tradePrice
+	AuctionEnd   time.Time
+	ResumeStatus entities.TradingStatus
+	OrderGroups  []orderGroup
+
+	// Clock of the book, which a circuit breaker tripped right after the
+	// restore starts its halt from
+	Clock time.Time
+
+	MatchingAlgorithm entities.MatchingAlgorithm
 }
 
 type priceOrderIdKey struct {
-	price   decimal.Decimal
-	orderId int64
+	price decimal.Decimal
+
+	// Time priority within the price, the order id breaks ties
+	priority int64
+	orderId  int64
 }
 
 func NewOrderBook(product *entities.Product) *OrderBook {
@@ -68,37 +147,184 @@ func NewOrderBook(product *entities.Product) *OrderBook {
 	}
 
 	orderBook := &OrderBook{
-		product:       *product,
-		depths:        map[entities.Side]*depth{entities.SideBuy: bids, entities.SideSell: asks},
+		product: *product,
+		depths:  map[entities.Side]*depth{entities.SideBuy: bids, entities.SideSell: asks},
+		stopBooks: map[entities.Side]*stopBook{
+			entities.SideBuy:  newStopBook(entities.SideBuy),
+			entities.SideSell: newStopBook(entities.SideSell),
+		},
+		expiries:      newExpiryQueue(),
 		orderIdWindow: newWindow(0, orderIdWindowCap),
+
+		clientUuidWindow: newClientUuidWindow(orderIdWindowCap),
+
+		tradingStatus: product.TradingStatus,
+		priceLimits:   product.PriceLimits,
+		pegs:          map[int64]entities.Side{},
+		groups:        map[int64]*orderGroup{},
+		legGroups:     map[int64]int64{},
+
+		matchingAlgorithm: product.MatchingAlgorithm,
 	}
 
 	return orderBook
 }
 
-func (o *OrderBook) ApplyOrder(order *entities.Order) (logs []Log) {
-	// prevent orders from being submitted repeatedly to the matching enginge
+func (o *OrderBook) ApplyOrder(order *entities.Order) []Log {
+	return o.applyGroupRules(o.applyOrder(order))
+}
+
+func (o *OrderBook) applyOrder(order *entities.Order) (logs []Log) {
+	takerOrder := newBookOrder(order)
+
+	// prevent orders from being submitted repeatedly to the matching enginge,
+	// an order seen before was already applied and reported. An order the
+	// window slid past can't be told from a new one unless it's still on the
+	// book, it's rejected rather than left unanswered.
 	err := o.orderIdWindow.put(int64(order.ID))
 	if err != nil {
-		log.Error(err)
+		log.Warnf("order %v skipped: %v", order.ID, err)
+		if errors.Is(err, errWindowExpired) && !o.hasOrder(takerOrder) {
+			rejectedLog := newRejectedLog(o.nextLogSeq(), int64(o.product.ID), takerOrder, entities.RejectReasonDuplicate)
+			logs = append(logs, rejectedLog)
+		}
 		return logs
 	}
 
-	takerOrder := newBookOrder(order)
+	logs = o.expireOrders(order.CreatedAt, logs)
+	logs = o.endAuction(logs)
 
-	// If it's a Market-Buy Order, set price to infinite high, and if it's a market sell
-	// set price to zero, which ensures that prices will cross
-	if takerOrder.Type == entities.MARKET_ORDER {
-		if takerOrder.Side == entities.SideBuy {
-			takerOrder.Price = decimal.NewFromFloat(math.MaxFloat32)
-		} else {
-			takerOrder.Price = decimal.Zero
+	if reason, rejected := o.rejectReason(order); rejected {
+		rejectedLog := newRejectedLog(o.nextLogSeq(), int64(o.product.ID), takerOrder, reason)
+		return append(logs, rejectedLog)
+	}
+
+	receivedLog := newReceivedLog(o.nextLogSeq(), int64(o.product.ID), takerOrder)
+	logs = append(logs, receivedLog)
+
+	// A different order reusing the client uuid of a recent order of the
+	// user is a duplicate
+	if len(order.ClientUuid) > 0 {
+		if err := o.clientUuidWindow.put(takerOrder.UserId, order.ClientUuid); err != nil {
+			doneLog := newDoneLog(o.nextLogSeq(), int64(o.product.ID), takerOrder, takerOrder.Size, entities.DoneReasonDuplicate)
+			return append(logs, doneLog)
+		}
+	}
+
+	if !o.acceptsOrder(takerOrder) {
+		doneLog := newDoneLog(o.nextLogSeq(), int64(o.product.ID), takerOrder, takerOrder.Size, entities.DoneReasonTradingStatus)
+		return append(logs, doneLog)
+	}
+
+	// A GTT order which has already expired never reaches the book
+	if takerOrder.TimeInForce == entities.TimeInForceGTT && !takerOrder.ExpireTime.After(o.clock) {
+		doneLog := newDoneLog(o.nextLogSeq(), int64(o.product.ID), takerOrder, takerOrder.Size, entities.DoneReasonExpired)
+		return append(logs, doneLog)
+	}
+
+	// A pegged order enters the book at the price it tracks
+	if len(takerOrder.PegType) > 0 {
+		if price, ok := o.pegPrice(takerOrder); ok {
+			takerOrder.Price = price
+		}
+		o.pegs[takerOrder.OrderId] = takerOrder.Side
+	}
+
+	if o.outsidePriceBand(takerOrder) {
+		doneLog := newDoneLog(o.nextLogSeq(), int64(o.product.ID), takerOrder, takerOrder.Size, entities.DoneReasonPriceBand)
+		return append(logs, doneLog)
+	}
+
+	// Stop orders wait in the trigger book until a trade crosses their
+	// stop price
+	if takerOrder.Type.IsStop() {
+		// A trailing stop starts from the reference price of the book
+		if takerOrder.isTrailing() {
+			takerOrder.trail(o.referencePrice())
+		}
+		o.stopBooks[takerOrder.Side].add(*takerOrder)
+		if takerOrder.TimeInForce == entities.TimeInForceGTT {
+			o.expiries.add(takerOrder)
+		}
+		return logs
+	}
+
+	logs = o.matchOrder(takerOrder, logs)
+	return o.activateStopOrders(logs)
+}
+
+// rejectReason tells why the book turns away an order without taking it: a
+// type or side it doesn't know, or trading being halted
+func (o *OrderBook) rejectReason(order *entities.Order) (entities.RejectReason, bool) {
+	if _, err := entities.NewOrderTypeFromString(string(order.Type)); err != nil {
+		return entities.RejectReasonUnknownType, true
+	}
+	if _, err := entities.NewSideFromString(string(order.Side)); err != nil {
+		return entities.RejectReasonUnknownType, true
+	}
+	if o.tradingStatus == entities.TradingStatusHalted {
+		return entities.RejectReasonHalted, true
+	}
+	return "", false
+}
+
+// hasOrder reports whether the order is on the book, in a stop book or waiting
+// for a bracket entry
+func (o *OrderBook) hasOrder(order *BookOrder) bool {
+	if _, found := o.legGroups[order.OrderId]; found {
+		return true
+	}
+	for _, side := range []entities.Side{entities.SideBuy, entities.SideSell} {
+		if _, found := o.depths[side].orders[order.OrderId]; found {
+			return true
+		}
+		if _, found := o.stopBooks[side].orders[order.OrderId]; found {
+			return true
 		}
 	}
+	return false
+}
+
+// matchOrder matches the taker against the opposite depth, and then either puts
+// the remaining of a limit order on the book or finishes the taker.
+func (o *OrderBook) matchOrder(takerOrder *BookOrder, logs []Log) []Log {
+	// A FOK order is killed without any trade if it can't be filled completely
+	if takerOrder.TimeInForce == entities.TimeInForceFOK && !o.canFill(takerOrder) {
+		doneLog := newDoneLog(o.nextLogSeq(), int64(o.product.ID), takerOrder, takerOrder.Size, entities.DoneReasonCancelled)
+		return append(logs, doneLog)
+	}
+
+	// A post-only order is rejected instead of taking liquidity. Until
+	// trading reopens every limit order is, whether it is new, amended, a
+	// triggered stop or an armed exit of a bracket.
+	postOnly := takerOrder.PostOnly || o.tradingStatus == entities.TradingStatusPostOnly
+	if postOnly && takerOrder.Type == entities.LIMIT_ORDER && o.crossesBook(takerOrder) {
+		doneLog := newDoneLog(o.nextLogSeq(), int64(o.product.ID), takerOrder, takerOrder.Size, entities.DoneReasonPostOnly)
+		return append(logs, doneLog)
+	}
+
+	// During an auction orders rest on the book until the uncross
+	if o.tradingStatus == entities.TradingStatusAuction {
+		return o.restOrder(takerOrder, logs)
+	}
+
+	// Market orders trade at any price within the price band
+	if takerOrder.Type == entities.MARKET_ORDER {
+		takerOrder.Price = o.marketPrice(takerOrder.Side)
+	}
+
+	// Set when self-trade prevention cancels the taker
+	var takerCancelled bool
 
 	makerDepth := o.depths[takerOrder.Side.Opposite()]
-	for itr := makerDepth.queue.Iterator(); itr.Next(); {
-		makerOrder := makerDepth.orders[itr.Value().(int64)]
+	for {
+		// Always match against the head of the queue, since the makers
+		// are removed from the queue as they are filled
+		_, makerOrderId := makerDepth.queue.Min()
+		if makerOrderId == nil {
+			break
+		}
+		makerOrder := makerDepth.orders[makerOrderId.(int64)]
 
 		// check whether ther is price crossing between the taker and
 		// the maker
@@ -107,97 +333,560 @@ func (o *OrderBook) ApplyOrder(order *entities.Order) (logs []Log) {
 			break
 		}
 
+		// Pro-rata shares the whole price level between its orders
+		if o.matchingAlgorithm.IsProRata() {
+			var stop bool
+			logs, takerCancelled, stop = o.matchLevelProRata(takerOrder, makerOrder.Price, logs)
+			if stop {
+				break
+			}
+			continue
+		}
+
+		// The taker would trade with an order of the same user
+		if takerOrder.isSelfTrade(makerOrder) {
+			if o.takerSizeAt(takerOrder, makerOrder.Price).IsZero() {
+				break
+			}
+
+			var stop bool
+			logs, takerCancelled, stop = o.preventSelfTrade(takerOrder, makerOrder, logs)
+			if stop {
+				break
+			}
+			continue
+		}
+
 		// trade price
 		var price = makerOrder.Price
 
+		// A trade moving the price too fast starts a reopening auction
+		// instead, which the remaining of a limit taker joins
+		if o.tripsCircuitBreaker(price) {
+			logs = o.startCircuitBreakerAuction(logs)
+			break
+		}
+
+		// Take the minium size of taker at current price and maker as
 		// trade size
-		var size decimal.Decimal
+		takerSize := o.takerSizeAt(takerOrder, price)
+		if takerSize.IsZero() {
+			break
+		}
+		size := decimal.Min(takerSize, makerOrder.visibleSize())
 
-		if takerOrder.Type == entities.LIMIT_ORDER ||
-			(takerOrder.Type == entities.MARKET_ORDER && takerOrder.Side == entities.SideSell) {
-			if takerOrder.Size.IsZero() {
-				break
+		logs = o.trade(takerOrder, makerOrder, price, size, logs)
+	}
+
+	if takerCancelled {
+		if takerOrder.Type == entities.MARKET_ORDER {
+			takerOrder.Price = decimal.Zero
+		}
+		doneLog := newDoneLog(o.nextLogSeq(), int64(o.product.ID), takerOrder, takerOrder.Size, entities.DoneReasonSelfTrade)
+		logs = append(logs, doneLog)
+	} else if takerOrder.Type == entities.LIMIT_ORDER && takerOrder.Size.GreaterThan(decimal.Zero) &&
+		takerOrder.TimeInForce != entities.TimeInForceIOC && takerOrder.TimeInForce != entities.TimeInForceFOK {
+		// If taker has an uncompleted size, put taker in orderBook
+		logs = o.restOrder(takerOrder, logs)
+	} else {
+		var remainingSize = takerOrder.Size
+		var reason = entities.DoneReasonFilled
+
+		if takerOrder.Type == entities.MARKET_ORDER {
+			takerOrder.Price = decimal.Zero
+			if (takerOrder.ByFunds && takerOrder.Funds.GreaterThan(decimal.Zero)) ||
+				(!takerOrder.ByFunds && takerOrder.Size.GreaterThan(decimal.Zero)) {
+				reason = entities.DoneReasonCancelled
 			}
+		} else if takerOrder.Size.GreaterThan(decimal.Zero) {
+			// the remaining of an IOC or FOK limit order is cancelled
+			reason = entities.DoneReasonCancelled
+		}
+
+		doneLog := newDoneLog(o.nextLogSeq(), int64(o.product.ID), takerOrder, remainingSize, reason)
+		logs = append(logs, doneLog)
+	}
+	return logs
+}
 
-			// Take the minium size of taker and maker as trade size
-			size = decimal.Min(takerOrder.Size, makerOrder.Size)
+// trade trades size between the taker and the maker at price
+func (o *OrderBook) trade(takerOrder, makerOrder *BookOrder, price, size decimal.Decimal, logs []Log) []Log {
+	// Adjust the size and the funds of taker order
+	if takerOrder.tracksFunds() {
+		takerOrder.Funds = takerOrder.Funds.Sub(size.Mul(price))
+	}
+	if takerOrder.tracksSize() {
+		takerOrder.Size = takerOrder.Size.Sub(size)
+	}
 
-			// Adjust the size of taker order
-			takerOrder.Size = takerOrder.Size.Sub(size)
+	// Adjust the size of maker order
+	err := o.depths[makerOrder.Side].decrSize(makerOrder.OrderId, size)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-		} else if takerOrder.Type == entities.MARKET_ORDER && takerOrder.Side == entities.SideBuy {
-			if takerOrder.Funds.IsZero() {
-				break
-			}
+	// mathed, write a log
+	matchLog := newMatchLog(o.nextLogSeq(), int64(o.product.ID), o.nextTradeSeq(), takerOrder, makerOrder, price, size)
+	logs = append(logs, matchLog)
+	o.recordTrade(price)
+
+	// Maker is filled
+	if makerOrder.Size.IsZero() {
+		doneLog := newDoneLog(o.nextLogSeq(), int64(o.product.ID), makerOrder, makerOrder.Size, entities.DoneReasonFilled)
+		logs = append(logs, doneLog)
+	} else if makerOrder.visibleSize().IsZero() {
+		logs = o.refillOrder(makerOrder, logs)
+	}
+	return logs
+}
+
+// matchLevelProRata matches the taker against the orders resting at price, which
+// share the trade in proportion to their visible size. The makers of the same
+// user as the taker are dealt with first. It reports whether the taker got
+// cancelled, and whether the matching of the taker has to stop.
+func (o *OrderBook) matchLevelProRata(takerOrder *BookOrder, price decimal.Decimal, logs []Log) ([]Log, bool, bool) {
+	makerDepth := o.depths[takerOrder.Side.Opposite()]
 
-			// Calculate the size of taker at current price
-			takerSize := takerOrder.Funds.Div(price).Truncate(o.product.BaseScale)
-			if takerSize.IsZero() {
+	for {
+		var selfTradeMaker *BookOrder
+		for _, makerOrder := range makerDepth.ordersAt(price) {
+			if takerOrder.isSelfTrade(makerOrder) {
+				selfTradeMaker = makerOrder
 				break
 			}
+		}
+		if selfTradeMaker == nil {
+			break
+		}
+		if o.takerSizeAt(takerOrder, price).IsZero() {
+			return logs, false, true
+		}
+
+		var takerCancelled, stop bool
+		logs, takerCancelled, stop = o.preventSelfTrade(takerOrder, selfTradeMaker, logs)
+		if stop {
+			return logs, takerCancelled, true
+		}
+	}
 
-			// Taker the minimum size of the taker and maker as trade
-			// size
-			size = decimal.Min(takerSize, makerOrder.Size)
-			funds := size.Mul(price)
+	makerOrders := makerDepth.ordersAt(price)
+	if len(makerOrders) == 0 {
+		return logs, false, false
+	}
 
-			// Adjust the funds of taker order
-			takerOrder.Funds = takerOrder.Funds.Sub(funds)
-		} else {
-			log.Fatal("unknown order type and side combination")
+	// A trade moving the price too fast starts a reopening auction instead,
+	// which the remaining of a limit taker joins
+	if o.tripsCircuitBreaker(price) {
+		return o.startCircuitBreakerAuction(logs), false, true
+	}
+
+	takerSize := o.takerSizeAt(takerOrder, price)
+	if takerSize.IsZero() {
+		return logs, false, true
+	}
+
+	sizes := o.allocate(takerSize, makerOrders)
+	for i, makerOrder := range makerOrders {
+		if sizes[i].IsZero() {
+			continue
 		}
+		logs = o.trade(takerOrder, makerOrder, price, sizes[i], logs)
+	}
+	return logs, false, false
+}
+
+// allocate shares size between the orders of a price level, given in the order
+// of the queue. Every order gets its share of size in proportion to its visible
+// size, truncated to the base scale. What the truncation leaves is handed out a
+// lot of the base scale at a time, to the orders in the order of the queue. With
+// top FIFO priority the first order is filled first up to its visible size.
+func (o *OrderBook) allocate(size decimal.Decimal, orders []*BookOrder) []decimal.Decimal {
+	sizes := make([]decimal.Decimal, len(orders))
+
+	first := 0
+	if o.matchingAlgorithm == entities.MatchingAlgorithmProRataTopFifo {
+		sizes[0] = decimal.Min(size, orders[0].visibleSize())
+		size = size.Sub(sizes[0])
+		first = 1
+	}
+
+	levelSize := decimal.Zero
+	for _, order := range orders[first:] {
+		levelSize = levelSize.Add(order.visibleSize())
+	}
+	if size.IsZero() || levelSize.IsZero() {
+		return sizes
+	}
+
+	if size.GreaterThanOrEqual(levelSize) {
+		for i := first; i < len(orders); i++ {
+			sizes[i] = orders[i].visibleSize()
+		}
+		return sizes
+	}
+
+	remaining := size
+	for i := first; i < len(orders); i++ {
+		sizes[i] = size.Mul(orders[i].visibleSize()).Div(levelSize).Truncate(o.product.BaseScale)
+		remaining = remaining.Sub(sizes[i])
+	}
+
+	lot := decimal.New(1, -o.product.BaseScale)
+	for remaining.GreaterThan(decimal.Zero) {
+		for i := first; i < len(orders) && remaining.GreaterThan(decimal.Zero); i++ {
+			extra := decimal.Min(lot, remaining, orders[i].visibleSize().Sub(sizes[i]))
+			sizes[i] = sizes[i].Add(extra)
+			remaining = remaining.Sub(extra)
+		}
+	}
+	return sizes
+}
 
-		// Adjust the size of maker order
-		err := makerDepth.decrSize(makerOrder.OrderId, size)
+// restOrder puts the remaining of a limit order on the book. Only the first
+// slice of an iceberg order is visible.
+func (o *OrderBook) restOrder(order *BookOrder, logs []Log) []Log {
+	order.ReserveSize = decimal.Zero
+	if order.DisplaySize.GreaterThan(decimal.Zero) && order.Size.GreaterThan(order.DisplaySize) {
+		order.ReserveSize = order.Size.Sub(order.DisplaySize)
+	}
+
+	openLog := newOpenLog(o.nextLogSeq(), int64(o.product.ID), order)
+	order.Priority = openLog.Sequence
+	o.depths[order.Side].add(*order)
+	if order.TimeInForce == entities.TimeInForceGTT {
+		o.expiries.add(order)
+	}
+	return append(logs, openLog)
+}
+
+// preventSelfTrade applies the self-trade prevention mode of the taker to a
+// maker of the same user. It reports whether the taker got cancelled, and
+// whether the matching of the taker has to stop.
+func (o *OrderBook) preventSelfTrade(takerOrder, makerOrder *BookOrder, logs []Log) ([]Log, bool, bool) {
+	makerDepth := o.depths[makerOrder.Side]
+
+	cancelMaker := func() {
+		remainingSize := makerOrder.Size
+		err := makerDepth.decrSize(makerOrder.OrderId, makerOrder.Size)
 		if err != nil {
 			log.Fatal(err)
 		}
+		doneLog := newDoneLog(o.nextLogSeq(), int64(o.product.ID), makerOrder, remainingSize, entities.DoneReasonSelfTrade)
+		logs = append(logs, doneLog)
+	}
 
-		// mathed, write a log
-		matchLog := newMatchLog(o.nextLogSeq(), int64(o.product.ID), o.nextTradeSeq(), takerOrder, makerOrder, price, size)
-		logs = append(logs, matchLog)
+	switch takerOrder.SelfTradePrevention {
+	case entities.SelfTradePreventionCancelNewest:
+		return logs, true, true
 
-		// Maker is filled
-		if makerOrder.Size.IsZero() {
-			doneLog := newDoneLog(o.nextLogSeq(), int64(o.product.ID), makerOrder, makerOrder.Size, entities.DoneReasonFilled)
-			logs = append(logs, doneLog)
+	case entities.SelfTradePreventionCancelOldest:
+		cancelMaker()
+		return logs, false, false
+
+	case entities.SelfTradePreventionCancelBoth:
+		cancelMaker()
+		return logs, true, true
+
+	case entities.SelfTradePreventionDecrementAndCancel:
+		size := decimal.Min(o.takerSizeAt(takerOrder, makerOrder.Price), makerOrder.visibleSize())
+		if size.Equal(makerOrder.Size) {
+			cancelMaker()
+		} else {
+			oldSize := makerOrder.displayedSize()
+			err := makerDepth.decrSize(makerOrder.OrderId, size)
+			if err != nil {
+				log.Fatal(err)
+			}
+			makerOrder.TotalSize = makerOrder.TotalSize.Sub(size)
+			changeLog := newChangeLog(o.nextLogSeq(), int64(o.product.ID), makerOrder, oldSize, makerOrder.displayedSize())
+			logs = append(logs, changeLog)
+
+			if makerOrder.visibleSize().IsZero() {
+				logs = o.refillOrder(makerOrder, logs)
+			}
 		}
+
+		if takerOrder.tracksFunds() {
+			takerOrder.Funds = takerOrder.Funds.Sub(size.Mul(makerOrder.Price))
+		}
+		if takerOrder.tracksSize() {
+			takerOrder.Size = takerOrder.Size.Sub(size)
+			takerOrder.TotalSize = takerOrder.TotalSize.Sub(size)
+		}
+
+		if o.takerSizeAt(takerOrder, makerOrder.Price).IsZero() {
+			return logs, true, true
+		}
+		return logs, false, false
 	}
 
-	if takerOrder.Type == entities.LIMIT_ORDER && takerOrder.Size.GreaterThan(decimal.Zero) {
-		// If taker has an uncompleted size, put taker in orderBook
-		o.depths[takerOrder.Side].add(*takerOrder)
+	log.Fatalf("unknown self trade prevention: %v", takerOrder.SelfTradePrevention)
+	return logs, false, true
+}
 
-		openLog := newOpenLog(o.nextLogSeq(), int64(o.product.ID), takerOrder)
-		logs = append(logs, openLog)
+// refillOrder shows the next slice of an iceberg order whose visible size is
+// used up. The refill loses its time priority and goes to the back of the queue.
+func (o *OrderBook) refillOrder(order *BookOrder, logs []Log) []Log {
+	size := decimal.Min(order.DisplaySize, order.Size)
+
+	logSeq := o.nextLogSeq()
+	o.depths[order.Side].requeue(order.OrderId, logSeq, order.Size.Sub(size))
+
+	openLog := newOpenLog(logSeq, int64(o.product.ID), order)
+	return append(logs, openLog)
+}
+
+// takerSizeAt returns the size the taker is still able to trade at price. The
+// funds of a market order buy what they pay for at price, and sell what it
+// takes to receive them.
+func (o *OrderBook) takerSizeAt(takerOrder *BookOrder, price decimal.Decimal) decimal.Decimal {
+	if !takerOrder.tracksFunds() {
+		return takerOrder.Size
+	}
+	if takerOrder.Funds.LessThanOrEqual(decimal.Zero) {
+		return decimal.Zero
+	}
+
+	var size decimal.Decimal
+	if takerOrder.Side == entities.SideBuy {
+		size = takerOrder.Funds.Div(price).Truncate(o.product.BaseScale)
 	} else {
-		var remainingSize = takerOrder.Size
-		var reason = entities.DoneReasonFilled
+		size = takerOrder.Funds.Div(price).RoundCeil(o.product.BaseScale)
+	}
+	if takerOrder.tracksSize() {
+		size = decimal.Min(size, takerOrder.Size)
+	}
+	return size
+}
 
-		if takerOrder.Type == entities.MARKET_ORDER {
-			takerOrder.Price = decimal.Zero
-			if (takerOrder.Side == entities.SideSell && takerOrder.Size.GreaterThan(decimal.Zero)) ||
-				(takerOrder.Side == entities.SideBuy && takerOrder.Funds.GreaterThan(decimal.Zero)) {
-				reason = entities.DoneReasonCancelled
+// crossesBook reports whether a limit order would trade against the best order
+// of the opposite depth
+func (o *OrderBook) crossesBook(takerOrder *BookOrder) bool {
+	_, makerOrderId := o.depths[takerOrder.Side.Opposite()].queue.Min()
+	if makerOrderId == nil {
+		return false
+	}
+
+	makerOrder := o.depths[takerOrder.Side.Opposite()].orders[makerOrderId.(int64)]
+	return (takerOrder.Side == entities.SideBuy && takerOrder.Price.GreaterThanOrEqual(makerOrder.Price)) ||
+		(takerOrder.Side == entities.SideSell && takerOrder.Price.LessThanOrEqual(makerOrder.Price))
+}
+
+// canFill reports whether the crossing orders of the opposite depth are enough
+// to fill the taker completely
+func (o *OrderBook) canFill(takerOrder *BookOrder) bool {
+	remainingSize := takerOrder.Size
+	remainingFunds := takerOrder.Funds
+
+	price := takerOrder.Price
+	if takerOrder.Type == entities.MARKET_ORDER {
+		price = o.marketPrice(takerOrder.Side)
+	}
+
+	makerDepth := o.depths[takerOrder.Side.Opposite()]
+	for itr := makerDepth.queue.Iterator(); itr.Next(); {
+		makerOrder := makerDepth.orders[itr.Value().(int64)]
+
+		if (takerOrder.Side == entities.SideBuy && price.LessThan(makerOrder.Price)) ||
+			(takerOrder.Side == entities.SideSell && price.GreaterThan(makerOrder.Price)) {
+			break
+		}
+
+		// Orders of the same user never trade with each other. Only cancelling
+		// the resting orders lets the taker go on matching
+		if takerOrder.isSelfTrade(makerOrder) {
+			if takerOrder.SelfTradePrevention == entities.SelfTradePreventionCancelOldest {
+				continue
 			}
+			return false
 		}
 
-		doneLog := newDoneLog(o.nextLogSeq(), int64(o.product.ID), takerOrder, remainingSize, reason)
+		// A market order by funds fills once the makers are worth its funds,
+		// the other orders once the makers add up to their size. What was
+		// held for the other one bounds a market order as well.
+		if takerOrder.ByFunds {
+			size := makerOrder.Size
+			if takerOrder.Side == entities.SideSell {
+				size = decimal.Min(size, remainingFunds.Div(makerOrder.Price).RoundCeil(o.product.BaseScale))
+			}
+			remainingFunds = remainingFunds.Sub(makerOrder.Size.Mul(makerOrder.Price))
+			if takerOrder.tracksSize() {
+				remainingSize = remainingSize.Sub(size)
+				if remainingSize.LessThan(decimal.Zero) {
+					return false
+				}
+			}
+			if remainingFunds.LessThanOrEqual(decimal.Zero) {
+				return true
+			}
+		} else {
+			size := decimal.Min(makerOrder.Size, remainingSize)
+			remainingSize = remainingSize.Sub(size)
+			if takerOrder.tracksFunds() {
+				remainingFunds = remainingFunds.Sub(size.Mul(makerOrder.Price))
+				if remainingFunds.LessThan(decimal.Zero) {
+					return false
+				}
+			}
+			if remainingSize.IsZero() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Tick advances the clock of the book to now, expires the orders whose time has
+// passed and ends the auction whose end has passed
+func (o *OrderBook) Tick(now time.Time) []Log {
+	logs := o.expireOrders(now, nil)
+	logs = o.endAuction(logs)
+	return o.applyGroupRules(logs)
+}
+
+// expireOrders advances the clock of the book to now, and cancels every GTT
+// order which expired by then
+func (o *OrderBook) expireOrders(now time.Time, logs []Log) []Log {
+	if now.After(o.clock) {
+		o.clock = now
+	}
+
+	for {
+		orderId, side, found := o.expiries.popExpired(o.clock)
+		if !found {
+			break
+		}
+
+		stopOrder, found := o.stopBooks[side].remove(orderId)
+		if found {
+			doneLog := newDoneLog(o.nextLogSeq(), int64(o.product.ID), stopOrder, stopOrder.Size, entities.DoneReasonExpired)
+			logs = append(logs, doneLog)
+			continue
+		}
+
+		bookOrder, found := o.depths[side].orders[orderId]
+		if !found {
+			// already filled or cancelled
+			continue
+		}
+
+		remainingSize := bookOrder.Size
+		err := o.depths[side].decrSize(orderId, bookOrder.Size)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		doneLog := newDoneLog(o.nextLogSeq(), int64(o.product.ID), bookOrder, remainingSize, entities.DoneReasonExpired)
 		logs = append(logs, doneLog)
 	}
 	return logs
 }
 
+// activateStopOrders walks through the trades in logs and activates every stop
+// order crossed by a trade price. An activated order is matched the same way as
+// a new order, so the trades it makes can activate further stop orders.
+func (o *OrderBook) activateStopOrders(logs []Log) []Log {
+	for i := 0; i < len(logs); i++ {
+		matchLog, ok := logs[i].(*MatchLog)
+		if !ok {
+			continue
+		}
+
+		for _, side := range []entities.Side{entities.SideBuy, entities.SideSell} {
+			o.stopBooks[side].trail(matchLog.Price)
+			for {
+				stopOrder := o.stopBooks[side].popTriggered(matchLog.Price)
+				if stopOrder == nil {
+					break
+				}
+
+				activateLog := newActivateLog(o.nextLogSeq(), int64(o.product.ID), stopOrder)
+				logs = append(logs, activateLog)
+
+				stopOrder.Type = stopOrder.Type.Triggered()
+				if !o.acceptsOrder(stopOrder) {
+					doneLog := newDoneLog(o.nextLogSeq(), int64(o.product.ID), stopOrder, stopOrder.Size, entities.DoneReasonTradingStatus)
+					logs = append(logs, doneLog)
+					continue
+				}
+				if o.outsidePriceBand(stopOrder) {
+					doneLog := newDoneLog(o.nextLogSeq(), int64(o.product.ID), stopOrder, stopOrder.Size, entities.DoneReasonPriceBand)
+					logs = append(logs, doneLog)
+					continue
+				}
+				logs = o.matchOrder(stopOrder, logs)
+			}
+		}
+	}
+	return logs
+}
+
+// CancelOrder cancels the order whatever the trading status, a halt keeps new
+// orders out but never the cancels of the orders on the book
 func (o *OrderBook) CancelOrder(order *entities.Order) (logs []Log) {
 	_ = o.orderIdWindow.put(int64(order.ID))
 
-	bookOrder, found := o.depths[order.Side].orders[int64(order.ID)]
+	logs = o.cancelOrder(int64(order.ID), order.Side, logs)
+	return o.applyGroupRules(logs)
+}
+
+// MassCancel cancels every order of the user in a single step, only the ones of
+// side if it's not nil. The orders of a side are cancelled in the order of the
+// queue, so that a replay gives the same logs. Like a cancel it goes through
+// whatever the trading status.
+func (o *OrderBook) MassCancel(userId int64, side *entities.Side) (logs []Log) {
+	sides := []entities.Side{entities.SideBuy, entities.SideSell}
+	if side != nil {
+		sides = []entities.Side{*side}
+	}
+
+	for _, side := range sides {
+		var orderIds []int64
+		orderIds = append(orderIds, userOrderIds(o.stopBooks[side].queue, o.stopBooks[side].orders, userId)...)
+		orderIds = append(orderIds, userOrderIds(o.depths[side].queue, o.depths[side].orders, userId)...)
+
+		for _, orderId := range orderIds {
+			logs = o.cancelOrder(orderId, side, logs)
+		}
+	}
+	return o.applyGroupRules(logs)
+}
+
+// MassQuote replaces the quotes of the user in a single step. The quotes on the
+// book are cancelled in the order of the queues, then the new ones are applied
+// in the order they were sent. Quotes are kept while trading is halted, and the
+// new ones are rejected like any order.
+func (o *OrderBook) MassQuote(userId int64, orders []*entities.Order) (logs []Log) {
+	if o.tradingStatus != entities.TradingStatusHalted {
+		for _, side := range []entities.Side{entities.SideBuy, entities.SideSell} {
+			for _, orderId := range userOrderIds(o.depths[side].queue, o.depths[side].orders, userId) {
+				if o.depths[side].orders[orderId].Quote {
+					logs = o.cancelOrder(orderId, side, logs)
+				}
+			}
+		}
+	}
+
+	for _, order := range orders {
+		logs = append(logs, o.applyOrder(order)...)
+	}
+	return o.applyGroupRules(logs)
+}
+
+func (o *OrderBook) cancelOrder(orderId int64, side entities.Side, logs []Log) []Log {
+	stopOrder, found := o.stopBooks[side].remove(orderId)
+	if found {
+		doneLog := newDoneLog(o.nextLogSeq(), int64(o.product.ID), stopOrder, stopOrder.Size, entities.DoneReasonCancelled)
+		return append(logs, doneLog)
+	}
+
+	bookOrder, found := o.depths[side].orders[orderId]
 	if !found {
-		return logs
+		return o.cancelPendingLeg(orderId, logs)
 	}
 
 	remainingSize := bookOrder.Size
-	err := o.depths[order.Side].decrSize(int64(order.ID), bookOrder.Size)
+	err := o.depths[side].decrSize(orderId, bookOrder.Size)
 	if err != nil {
 		panic(err)
 	}
@@ -206,12 +895,128 @@ func (o *OrderBook) CancelOrder(order *entities.Order) (logs []Log) {
 	return append(logs, doneLog)
 }
 
+// userOrderIds returns the ids of the orders of the user in the order of the
+// queue
+func userOrderIds(queue *treemap.Map, orders map[int64]*BookOrder, userId int64) []int64 {
+	var orderIds []int64
+	for itr := queue.Iterator(); itr.Next(); {
+		orderId := itr.Value().(int64)
+		if orders[orderId].UserId == userId {
+			orderIds = append(orderIds, orderId)
+		}
+	}
+	return orderIds
+}
+
+// AmendOrder changes the price and the size of a resting order. The size of the
+// amend is the new size of the whole order, including what is already filled.
+// Decreasing the size keeps the time priority of the order, while a new price or
+// a bigger size takes it off the book and matches it again like a new order.
+func (o *OrderBook) AmendOrder(order *entities.Order) (logs []Log) {
+	bookOrder, found := o.depths[order.Side].orders[int64(order.ID)]
+	if !found {
+		// already filled or cancelled
+		return o.rejectAmend(order, entities.RejectReasonNotFound, logs)
+	}
+
+	// Without the total size there is no telling how much of the order is
+	// filled
+	if bookOrder.TotalSize.IsZero() {
+		log.Warnf("order %v can't be amended, total size unknown", bookOrder.OrderId)
+		return o.rejectAmend(order, entities.RejectReasonUnknownSize, logs)
+	}
+
+	// An amend refused by the trading status or the price band, or of a
+	// pegged order, is answered with the order as it is, which settles what
+	// was held for the amend
+	if len(bookOrder.PegType) > 0 || (o.tradingStatus != "" && o.tradingStatus != entities.TradingStatusOpen &&
+		o.tradingStatus != entities.TradingStatusLimitOnly && o.tradingStatus != entities.TradingStatusAuction) ||
+		(!order.Price.Equal(bookOrder.Price) && o.outsidePriceBand(&BookOrder{Type: bookOrder.Type, Price: order.Price})) {
+		amendLog := newAmendLog(o.nextLogSeq(), int64(o.product.ID), bookOrder, bookOrder.Price, false)
+		return append(logs, amendLog)
+	}
+
+	filledSize := bookOrder.TotalSize.Sub(bookOrder.Size)
+	newSize := order.Size.Sub(filledSize)
+
+	// Nothing is left to rest once the filled size is taken out
+	if newSize.LessThanOrEqual(decimal.Zero) {
+		remainingSize := bookOrder.Size
+		err := o.depths[order.Side].decrSize(bookOrder.OrderId, bookOrder.Size)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		doneLog := newDoneLog(o.nextLogSeq(), int64(o.product.ID), bookOrder, remainingSize, entities.DoneReasonCancelled)
+		return o.applyGroupRules(append(logs, doneLog))
+	}
+
+	oldPrice := bookOrder.Price
+	if order.Price.Equal(bookOrder.Price) && newSize.LessThanOrEqual(bookOrder.Size) {
+		// The reserve of an iceberg order shrinks before its visible slice
+		decrSize := bookOrder.Size.Sub(newSize)
+		bookOrder.ReserveSize = bookOrder.ReserveSize.Sub(decimal.Min(decrSize, bookOrder.ReserveSize))
+
+		err := o.depths[order.Side].decrSize(bookOrder.OrderId, decrSize)
+		if err != nil {
+			log.Fatal(err)
+		}
+		bookOrder.TotalSize = order.Size
+
+		amendLog := newAmendLog(o.nextLogSeq(), int64(o.product.ID), bookOrder, oldPrice, false)
+		return append(logs, amendLog)
+	}
+
+	// A post-only order moved to a price crossing the book would be cancelled
+	// by the matching, the amend is refused instead and the order stays as it
+	// was
+	postOnly := bookOrder.PostOnly || o.tradingStatus == entities.TradingStatusPostOnly
+	if postOnly && bookOrder.Type == entities.LIMIT_ORDER &&
+		o.crossesBook(&BookOrder{Side: bookOrder.Side, Price: order.Price}) {
+		return o.rejectAmend(order, entities.RejectReasonPostOnly, logs)
+	}
+
+	err := o.depths[order.Side].decrSize(bookOrder.OrderId, bookOrder.Size)
+	if err != nil {
+		log.Fatal(err)
+	}
+	bookOrder.Size = newSize
+	bookOrder.TotalSize = order.Size
+	bookOrder.Price = order.Price
+
+	amendLog := newAmendLog(o.nextLogSeq(), int64(o.product.ID), bookOrder, oldPrice, true)
+	logs = append(logs, amendLog)
+
+	logs = o.matchOrder(bookOrder, logs)
+	logs = o.activateStopOrders(logs)
+	return o.applyGroupRules(logs)
+}
+
+// rejectAmend answers an amend the book can't apply, which releases what was
+// held for it
+func (o *OrderBook) rejectAmend(order *entities.Order, reason entities.RejectReason, logs []Log) []Log {
+	rejectedLog := newRejectedLog(o.nextLogSeq(), int64(o.product.ID), newBookOrder(order), reason)
+	rejectedLog.Amend = true
+	return append(logs, rejectedLog)
+}
+
 func (o *OrderBook) Snapshot() orderBooSnapShot {
+	// the snapshot is stored after the book has moved on
+	priceLimits := o.priceLimits
 	snapshot := orderBooSnapShot{
 		Orders:        make([]BookOrder, len(o.depths[entities.SideSell].orders)+len(o.depths[entities.SideBuy].orders)),
 		LogSeq:        o.LogSeq,
 		TradeSeq:      o.tradeSeq,
-		OrderIdWindow: o.orderIdWindow,
+		OrderIdWindow: o.orderIdWindow.copy(),
+		TradingStatus: o.tradingStatus,
+		PriceLimits:   &priceLimits,
+		RecentTrades:  o.recentTrades,
+		AuctionEnd:    o.auctionEnd,
+		ResumeStatus:  o.resumeStatus,
+
+		MatchingAlgorithm: o.matchingAlgorithm,
+		ClientUuidWindow:  o.clientUuidWindow.copy(),
+		Clock:             o.clock,
 	}
 
 	i := 0
@@ -225,6 +1030,21 @@ func (o *OrderBook) Snapshot() orderBooSnapShot {
 		i++
 	}
 
+	for _, stopBook := range []*stopBook{o.stopBooks[entities.SideBuy], o.stopBooks[entities.SideSell]} {
+		for _, order := range stopBook.orders {
+			snapshot.StopOrders = append(snapshot.StopOrders, *order)
+		}
+	}
+
+	groupIds := make([]int64, 0, len(o.groups))
+	for groupId := range o.groups {
+		groupIds = append(groupIds, groupId)
+	}
+	sort.Slice(groupIds, func(i, j int) bool { return groupIds[i] < groupIds[j] })
+	for _, groupId := range groupIds {
+		snapshot.OrderGroups = append(snapshot.OrderGroups, o.groups[groupId].copy())
+	}
+
 	return snapshot
 }
 
@@ -232,12 +1052,498 @@ func (o *OrderBook) Restore(snapshot *orderBooSnapShot) {
 	o.LogSeq = snapshot.LogSeq
 	o.tradeSeq = snapshot.TradeSeq
 	o.orderIdWindow = snapshot.OrderIdWindow
+	o.tradingStatus = snapshot.TradingStatus
+	if snapshot.PriceLimits != nil {
+		o.priceLimits = *snapshot.PriceLimits
+	}
+	o.recentTrades = snapshot.RecentTrades
+	o.auctionEnd = snapshot.AuctionEnd
+	o.resumeStatus = snapshot.ResumeStatus
+	o.clock = snapshot.Clock
+	if len(snapshot.MatchingAlgorithm) > 0 {
+		o.matchingAlgorithm = snapshot.MatchingAlgorithm
+	}
 	if o.orderIdWindow.Cap == 0 {
 		o.orderIdWindow = newWindow(0, orderIdWindowCap)
 	}
+	o.clientUuidWindow = snapshot.ClientUuidWindow
+	if o.clientUuidWindow.Cap == 0 {
+		o.clientUuidWindow.Cap = orderIdWindowCap
+	}
+	o.clientUuidWindow.restore()
 
 	for _, order := range snapshot.Orders {
 		o.depths[order.Side].add(order)
+		if len(order.PegType) > 0 {
+			o.pegs[order.OrderId] = order.Side
+		}
+		if order.TimeInForce == entities.TimeInForceGTT {
+			o.expiries.add(&order)
+		}
+	}
+
+	for _, order := range snapshot.StopOrders {
+		// Market buys were all by funds before it was tracked
+		if order.Type == entities.STOP_MARKET_ORDER && order.Side == entities.SideBuy && order.Size.IsZero() {
+			order.ByFunds = true
+		}
+		o.stopBooks[order.Side].add(order)
+		if order.TimeInForce == entities.TimeInForceGTT {
+			o.expiries.add(&order)
+		}
+	}
+
+	for i := range snapshot.OrderGroups {
+		group := snapshot.OrderGroups[i]
+		o.groups[group.GroupId] = &group
+		if group.EntryId != 0 {
+			o.legGroups[group.EntryId] = group.GroupId
+		}
+		for _, leg := range group.Legs {
+			o.legGroups[leg.OrderId] = group.GroupId
+		}
+		for _, exit := range group.PendingLegs {
+			o.legGroups[exit.OrderId] = group.GroupId
+		}
+	}
+}
+
+// SetTradingStatus changes what the book accepts. The orders on the book stay
+// where they are whatever the status, except at the end of an auction, which
+// uncrosses the book. An auction with an end time switches to open at the first
+// tick or order once its end has passed on the clock of the book.
+func (o *OrderBook) SetTradingStatus(status entities.TradingStatus, auctionEnd time.Time) (logs []Log) {
+	if _, err := entities.NewTradingStatusFromString(string(status)); err != nil {
+		log.Error(err)
+		return logs
+	}
+
+	// A status set by hand overrides the end of an auction in progress
+	o.auctionEnd = time.Time{}
+	o.resumeStatus = ""
+	if status == entities.TradingStatusAuction && !auctionEnd.IsZero() {
+		o.auctionEnd = auctionEnd
+		o.resumeStatus = entities.TradingStatusOpen
+	}
+
+	if status == o.currentTradingStatus() {
+		return logs
+	}
+	logs = o.switchTradingStatus(status, entities.StatusReasonControl, logs)
+	return o.applyGroupRules(logs)
+}
+
+func (o *OrderBook) currentTradingStatus() entities.TradingStatus {
+	if o.tradingStatus == "" {
+		return entities.TradingStatusOpen
+	}
+	return o.tradingStatus
+}
+
+// switchTradingStatus changes the trading status. Leaving an auction uncrosses
+// the book at the auction price first, which becomes the new reference of the
+// circuit breaker, and the stop orders crossed by the auction trades are
+// activated under the new status.
+func (o *OrderBook) switchTradingStatus(status entities.TradingStatus, reason entities.StatusReason, logs []Log) []Log {
+	oldStatus := o.currentTradingStatus()
+	if oldStatus == entities.TradingStatusAuction {
+		o.recentTrades = nil
+		logs = o.uncross(logs)
+	}
+
+	o.tradingStatus = status
+	statusLog := newStatusLog(o.nextLogSeq(), int64(o.product.ID), status, oldStatus, reason)
+	logs = append(logs, statusLog)
+
+	if oldStatus == entities.TradingStatusAuction {
+		logs = o.activateStopOrders(logs)
+	}
+	return logs
+}
+
+// SetPriceLimits changes the price band and the circuit breaker of the book. A
+// halt of the circuit breaker in progress runs to its end.
+func (o *OrderBook) SetPriceLimits(limits entities.PriceLimits) {
+	o.priceLimits = limits
+}
+
+// SetMatchingAlgorithm changes how trades are shared between the orders at a
+// price, from the next taker on
+func (o *OrderBook) SetMatchingAlgorithm(algorithm entities.MatchingAlgorithm) {
+	o.matchingAlgorithm = algorithm
+}
+
+// referencePrice is the price the price band is centered on, the last trade
+// price or else the mid price, zero if there is neither
+func (o *OrderBook) referencePrice() decimal.Decimal {
+	if len(o.recentTrades) > 0 {
+		return o.recentTrades[len(o.recentTrades)-1].Price
+	}
+
+	_, bestAskId := o.depths[entities.SideSell].queue.Min()
+	_, bestBidId := o.depths[entities.SideBuy].queue.Min()
+	if bestAskId == nil || bestBidId == nil {
+		return decimal.Zero
+	}
+	bestAsk := o.depths[entities.SideSell].orders[bestAskId.(int64)].Price
+	bestBid := o.depths[entities.SideBuy].orders[bestBidId.(int64)].Price
+	return bestAsk.Add(bestBid).Div(decimal.New(2, 0))
+}
+
+// outsidePriceBand reports whether the price of a limit order is further away
+// from the reference price than the price band allows
+func (o *OrderBook) outsidePriceBand(order *BookOrder) bool {
+	if order.Type != entities.LIMIT_ORDER {
+		return false
+	}
+
+	low, high, ok := o.priceLimits.PriceBand(o.referencePrice())
+	return ok && (order.Price.LessThan(low) || order.Price.GreaterThan(high))
+}
+
+// marketPrice is the worst price a market order of side trades at, the edge of
+// the price band, or else infinite high for a buy and zero for a sell, which
+// ensures that prices will cross
+func (o *OrderBook) marketPrice(side entities.Side) decimal.Decimal {
+	low, high, ok := o.priceLimits.PriceBand(o.referencePrice())
+	if side == entities.SideBuy {
+		if ok {
+			return high
+		}
+		return decimal.NewFromFloat(math.MaxFloat32)
+	}
+	return low
+}
+
+// recordTrade remembers the price of a trade, and forgets the trades the window
+// of the circuit breaker no longer needs
+func (o *OrderBook) recordTrade(price decimal.Decimal) {
+	o.recentTrades = append(o.recentTrades, tradePrice{Time: o.clock, Price: price})
+
+	windowStart := o.circuitBreakerWindowStart()
+	i := 0
+	for i < len(o.recentTrades)-1 && !o.recentTrades[i+1].Time.After(windowStart) {
+		i++
+	}
+	o.recentTrades = o.recentTrades[i:]
+}
+
+func (o *OrderBook) circuitBreakerWindowStart() time.Time {
+	return o.clock.Add(-time.Duration(o.priceLimits.CircuitBreakerWindowSeconds) * time.Second)
+}
+
+// tripsCircuitBreaker reports whether a trade at price would move the price
+// more than the circuit breaker allows within its window. The move is measured
+// from the last trade before the window, or else from the first one within it.
+func (o *OrderBook) tripsCircuitBreaker(price decimal.Decimal) bool {
+	if o.priceLimits.CircuitBreakerPercent.LessThanOrEqual(decimal.Zero) || len(o.recentTrades) == 0 {
+		return false
+	}
+
+	windowStart := o.circuitBreakerWindowStart()
+	referencePrice := o.recentTrades[0].Price
+	for i := 1; i < len(o.recentTrades) && !o.recentTrades[i].Time.After(windowStart); i++ {
+		referencePrice = o.recentTrades[i].Price
+	}
+	if referencePrice.IsZero() {
+		return false
+	}
+
+	move := price.Sub(referencePrice).Abs().Mul(decimal.New(100, 0)).Div(referencePrice)
+	return move.GreaterThan(o.priceLimits.CircuitBreakerPercent)
+}
+
+// startCircuitBreakerAuction stops the continuous trading for a reopening
+// auction, which uncrosses at the first tick or order once the halt time of the
+// circuit breaker has passed on the clock of the book
+func (o *OrderBook) startCircuitBreakerAuction(logs []Log) []Log {
+	o.resumeStatus = o.currentTradingStatus()
+	o.auctionEnd = o.clock.Add(time.Duration(o.priceLimits.CircuitBreakerHaltSeconds) * time.Second)
+	return o.switchTradingStatus(entities.TradingStatusAuction, entities.StatusReasonCircuitBreaker, logs)
+}
+
+// endAuction uncrosses the book once the end time of the auction has passed,
+// and switches to the status the auction was to end with
+func (o *OrderBook) endAuction(logs []Log) []Log {
+	if o.auctionEnd.IsZero() || o.clock.Before(o.auctionEnd) {
+		return logs
+	}
+
+	status := o.resumeStatus
+	o.auctionEnd = time.Time{}
+	o.resumeStatus = ""
+	return o.switchTradingStatus(status, entities.StatusReasonAuctionEnd, logs)
+}
+
+// uncross matches the crossing orders of the book at the auction price, which
+// trades the most size. Every trade of the auction is at that single price.
+// Within a price the orders trade in time priority, and the newest order of
+// each trade is the taker. A self-trade cancels the newest order, the size it
+// would have traded isn't made up for at another price, the orders which can't
+// trade at the auction price stay on the book.
+func (o *OrderBook) uncross(logs []Log) []Log {
+	price, size := o.auctionPrice()
+	if size.IsZero() {
+		return logs
+	}
+	return o.uncrossAt(price, size, logs)
+}
+
+func (o *OrderBook) uncrossAt(price, size decimal.Decimal, logs []Log) []Log {
+	bids := o.depths[entities.SideBuy]
+	asks := o.depths[entities.SideSell]
+
+	for size.GreaterThan(decimal.Zero) {
+		_, bidId := bids.queue.Min()
+		_, askId := asks.queue.Min()
+		if bidId == nil || askId == nil {
+			break
+		}
+		bid := bids.orders[bidId.(int64)]
+		ask := asks.orders[askId.(int64)]
+		if bid.Price.LessThan(price) || ask.Price.GreaterThan(price) {
+			break
+		}
+
+		takerOrder, makerOrder := bid, ask
+		if ask.Priority > bid.Priority {
+			takerOrder, makerOrder = ask, bid
+		}
+
+		if takerOrder.isSelfTrade(makerOrder) {
+			remainingSize := takerOrder.Size
+			err := o.depths[takerOrder.Side].decrSize(takerOrder.OrderId, takerOrder.Size)
+			if err != nil {
+				log.Fatal(err)
+			}
+			doneLog := newDoneLog(o.nextLogSeq(), int64(o.product.ID), takerOrder, remainingSize, entities.DoneReasonSelfTrade)
+			logs = append(logs, doneLog)
+			continue
+		}
+
+		tradeSize := decimal.Min(size, decimal.Min(bid.visibleSize(), ask.visibleSize()))
+		size = size.Sub(tradeSize)
+		oldSize := takerOrder.displayedSize()
+		for _, order := range []*BookOrder{bid, ask} {
+			err := o.depths[order.Side].decrSize(order.OrderId, tradeSize)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		matchLog := newMatchLog(o.nextLogSeq(), int64(o.product.ID), o.nextTradeSeq(), takerOrder, makerOrder, price, tradeSize)
+		logs = append(logs, matchLog)
+		o.recordTrade(price)
+
+		// Unlike in continuous trading the taker is on the book as well
+		for _, order := range []*BookOrder{makerOrder, takerOrder} {
+			if order.Size.IsZero() {
+				doneLog := newDoneLog(o.nextLogSeq(), int64(o.product.ID), order, order.Size, entities.DoneReasonFilled)
+				logs = append(logs, doneLog)
+			} else if order.visibleSize().IsZero() {
+				logs = o.refillOrder(order, logs)
+			} else if order == takerOrder && !order.Hidden {
+				changeLog := newChangeLog(o.nextLogSeq(), int64(o.product.ID), order, oldSize, order.displayedSize())
+				logs = append(logs, changeLog)
+			}
+		}
+	}
+	return logs
+}
+
+// auctionPrice returns the price at which the most size trades when the
+// crossing orders of the book are matched at a single price, and that size.
+// Ties go to the price leaving the least size unmatched, then to the price
+// closest to the reference price, then to the lowest price. The prices are
+// walked up once, the size to sell growing and the size to buy shrinking.
+func (o *OrderBook) auctionPrice() (decimal.Decimal, decimal.Decimal) {
+	bids := o.depths[entities.SideBuy]
+	asks := o.depths[entities.SideSell]
+
+	var totalBuySize decimal.Decimal
+	for _, order := range bids.orders {
+		totalBuySize = totalBuySize.Add(order.Size)
+	}
+
+	// The bids are walked from the lowest price as well
+	bidItr := bids.queue.Iterator()
+	hasBid := bidItr.Last()
+	askItr := asks.queue.Iterator()
+	hasAsk := askItr.First()
+	bidAt := func() *BookOrder { return bids.orders[bidItr.Value().(int64)] }
+	askAt := func() *BookOrder { return asks.orders[askItr.Value().(int64)] }
+
+	referencePrice := o.referencePrice()
+	var bestPrice, bestSize, bestSurplus decimal.Decimal
+
+	// Size of the bids below the price, and of the asks at or below it
+	var buySizeBelow, sellSize decimal.Decimal
+	for hasBid || hasAsk {
+		var price decimal.Decimal
+		if hasAsk && (!hasBid || askAt().Price.LessThanOrEqual(bidAt().Price)) {
+			price = askAt().Price
+		} else {
+			price = bidAt().Price
+		}
+
+		for hasAsk && askAt().Price.Equal(price) {
+			sellSize = sellSize.Add(askAt().Size)
+			hasAsk = askItr.Next()
+		}
+		buySize := totalBuySize.Sub(buySizeBelow)
+		for hasBid && bidAt().Price.Equal(price) {
+			buySizeBelow = buySizeBelow.Add(bidAt().Size)
+			hasBid = bidItr.Prev()
+		}
+
+		size := decimal.Min(buySize, sellSize)
+		if size.IsZero() {
+			if buySize.IsZero() {
+				break
+			}
+			continue
+		}
+		surplus := buySize.Sub(sellSize).Abs()
+
+		better := size.GreaterThan(bestSize)
+		if size.Equal(bestSize) {
+			if surplus.LessThan(bestSurplus) {
+				better = true
+			} else if surplus.Equal(bestSurplus) {
+				distance := price.Sub(referencePrice).Abs()
+				bestDistance := bestPrice.Sub(referencePrice).Abs()
+				better = distance.LessThan(bestDistance) ||
+					(distance.Equal(bestDistance) && price.LessThan(bestPrice))
+			}
+		}
+		if better {
+			bestPrice, bestSize, bestSurplus = price, size, surplus
+		}
+	}
+	return bestPrice, bestSize
+}
+
+// RepricePegs moves the pegged orders to the price they track once the best
+// prices have changed. A repriced order loses its time priority.
+func (o *OrderBook) RepricePegs() (logs []Log) {
+	if len(o.pegs) == 0 {
+		return logs
+	}
+
+	bestBid := o.unpeggedBestPrice(entities.SideBuy)
+	bestAsk := o.unpeggedBestPrice(entities.SideSell)
+	if bestBid.Equal(o.pegBestBid) && bestAsk.Equal(o.pegBestAsk) {
+		return logs
+	}
+	o.pegBestBid, o.pegBestAsk = bestBid, bestAsk
+
+	// in the order of the ids, so that a replay gives the same logs
+	orderIds := make([]int64, 0, len(o.pegs))
+	for orderId := range o.pegs {
+		orderIds = append(orderIds, orderId)
+	}
+	sort.Slice(orderIds, func(i, j int) bool { return orderIds[i] < orderIds[j] })
+
+	for _, orderId := range orderIds {
+		side := o.pegs[orderId]
+		order, found := o.depths[side].orders[orderId]
+		if !found {
+			// filled, cancelled or expired
+			delete(o.pegs, orderId)
+			continue
+		}
+
+		price, ok := o.pegPrice(order)
+		if !ok || price.Equal(order.Price) {
+			continue
+		}
+
+		oldPrice := order.Price
+		oldSize := order.displayedSize()
+		logSeq := o.nextLogSeq()
+		o.depths[side].reprice(orderId, price, logSeq)
+
+		changeLog := newChangeLog(logSeq, int64(o.product.ID), order, oldSize, order.displayedSize())
+		changeLog.Price = oldPrice
+		logs = append(logs, changeLog)
+	}
+	return logs
+}
+
+// pegPrice returns the price a pegged order tracks plus its offset, within the
+// limit of the order. Without the price it tracks the order keeps its price. A
+// pegged order never takes liquidity, so the price stays a tick away from the
+// opposite side. ok is false if there is no such price.
+func (o *OrderBook) pegPrice(order *BookOrder) (decimal.Decimal, bool) {
+	bestBid := o.unpeggedBestPrice(entities.SideBuy)
+	bestAsk := o.unpeggedBestPrice(entities.SideSell)
+
+	sameBest, oppositeBest := bestBid, bestAsk
+	if order.Side == entities.SideSell {
+		sameBest, oppositeBest = bestAsk, bestBid
+	}
+
+	var referencePrice decimal.Decimal
+	switch order.PegType {
+	case entities.PegTypePrimary:
+		referencePrice = sameBest
+	case entities.PegTypeMarket:
+		referencePrice = oppositeBest
+	case entities.PegTypeMid:
+		if bestBid.GreaterThan(decimal.Zero) && bestAsk.GreaterThan(decimal.Zero) {
+			referencePrice = bestBid.Add(bestAsk).Div(decimal.New(2, 0))
+		}
+	}
+
+	tickSize := o.product.TickSize()
+	price := order.Price
+	if referencePrice.GreaterThan(decimal.Zero) {
+		price = referencePrice.Add(order.PegOffset)
+		if order.Side == entities.SideBuy {
+			price = decimal.Min(price.Div(tickSize).Floor().Mul(tickSize), order.PegLimit)
+		} else {
+			price = decimal.Max(price.Div(tickSize).Ceil().Mul(tickSize), order.PegLimit)
+		}
+	}
+
+	_, makerOrderId := o.depths[order.Side.Opposite()].queue.Min()
+	if makerOrderId != nil {
+		makerPrice := o.depths[order.Side.Opposite()].orders[makerOrderId.(int64)].Price
+		if order.Side == entities.SideBuy && price.GreaterThanOrEqual(makerPrice) {
+			price = makerPrice.Sub(tickSize)
+		} else if order.Side == entities.SideSell && price.LessThanOrEqual(makerPrice) {
+			price = makerPrice.Add(tickSize)
+		}
+	}
+	return price, price.GreaterThan(decimal.Zero)
+}
+
+// unpeggedBestPrice returns the best price shown on a side which is not the
+// price of a pegged order, zero if there is none
+func (o *OrderBook) unpeggedBestPrice(side entities.Side) decimal.Decimal {
+	depth := o.depths[side]
+	for itr := depth.queue.Iterator(); itr.Next(); {
+		order := depth.orders[itr.Value().(int64)]
+		if len(order.PegType) == 0 && !order.Hidden {
+			return order.Price
+		}
+	}
+	return decimal.Zero
+}
+
+// acceptsOrder tells whether the trading status lets a new order in
+func (o *OrderBook) acceptsOrder(order *BookOrder) bool {
+	switch o.tradingStatus {
+	case entities.TradingStatusHalted, entities.TradingStatusCancelOnly:
+		return false
+	case entities.TradingStatusPostOnly, entities.TradingStatusLimitOnly:
+		return order.Type != entities.MARKET_ORDER && order.Type != entities.STOP_MARKET_ORDER
+	case entities.TradingStatusAuction:
+		// only orders which can wait for the uncross
+		return order.Type != entities.MARKET_ORDER && order.Type != entities.STOP_MARKET_ORDER &&
+			order.TimeInForce != entities.TimeInForceIOC && order.TimeInForce != entities.TimeInForceFOK
+	default:
+		return true
 	}
 }
 
@@ -263,7 +1569,44 @@ type depth struct {
 
 func (d *depth) add(order BookOrder) {
 	d.orders[order.OrderId] = &order
-	d.queue.Put(&priceOrderIdKey{order.Price, order.OrderId}, order.OrderId)
+	d.queue.Put(&priceOrderIdKey{order.Price, order.Priority, order.OrderId}, order.OrderId)
+}
+
+// requeue moves an order to the back of the queue of its price with a new
+// time priority and reserve size
+func (d *depth) requeue(orderId int64, priority int64, reserveSize decimal.Decimal) {
+	order := d.orders[orderId]
+	d.queue.Remove(&priceOrderIdKey{order.Price, order.Priority, order.OrderId})
+
+	order.Priority = priority
+	order.ReserveSize = reserveSize
+	d.queue.Put(&priceOrderIdKey{order.Price, order.Priority, order.OrderId}, order.OrderId)
+}
+
+// reprice moves an order to the back of the queue of a new price
+func (d *depth) reprice(orderId int64, price decimal.Decimal, priority int64) {
+	order := d.orders[orderId]
+	d.queue.Remove(&priceOrderIdKey{order.Price, order.Priority, order.OrderId})
+
+	order.Price = price
+	order.Priority = priority
+	d.queue.Put(&priceOrderIdKey{order.Price, order.Priority, order.OrderId}, order.OrderId)
+}
+
+// ordersAt returns the orders at price in the order of the queue
+func (d *depth) ordersAt(price decimal.Decimal) []*BookOrder {
+	var orders []*BookOrder
+	for itr := d.queue.Iterator(); itr.Next(); {
+		order := d.orders[itr.Value().(int64)]
+		if order.Price.GreaterThan(price) || order.Price.LessThan(price) {
+			if len(orders) > 0 {
+				break
+			}
+			continue
+		}
+		orders = append(orders, order)
+	}
+	return orders
 }
 
 func (d *depth) decrSize(orderId int64, size decimal.Decimal) error {
@@ -279,39 +1622,188 @@ func (d *depth) decrSize(orderId int64, size decimal.Decimal) error {
 	order.Size = order.Size.Sub(size)
 	if order.Size.IsZero() {
 		delete(d.orders, orderId)
-		d.queue.Remove(&priceOrderIdKey{order.Price, order.OrderId})
+		d.queue.Remove(&priceOrderIdKey{order.Price, order.Priority, order.OrderId})
 	}
 	return nil
 }
 
 type BookOrder struct {
-	OrderId int64
-	Size    decimal.Decimal
-	Funds   decimal.Decimal
-	Price   decimal.Decimal
-	Side    entities.Side
-	Type    entities.OrderType
+	OrderId     int64
+	UserId      int64
+	Size        decimal.Decimal
+	Funds       decimal.Decimal
+	Price       decimal.Decimal
+	StopPrice   decimal.Decimal
+	Side        entities.Side
+	Type        entities.OrderType
+	TimeInForce entities.TimeInForce
+	ExpireTime  time.Time
+	PostOnly    bool
+
+	SelfTradePrevention entities.SelfTradePrevention
+
+	// Visible slice of an iceberg order, zero if the whole order is visible
+	DisplaySize decimal.Decimal
+
+	// Part of Size kept out of the visible slice of an iceberg order
+	ReserveSize decimal.Decimal
+
+	// Hidden orders match but never show up on the book
+	Hidden bool
+
+	// Placed by a mass quote
+	Quote bool
+
+	// Time priority at the price, the sequence of the log which put the
+	// order or its current slice on the book
+	Priority int64
+
+	// Size of the order including what is filled, less the self-trade
+	// decrements. Zero for orders restored from a snapshot taken before it
+	// was tracked
+	TotalSize decimal.Decimal
+
+	// Price a pegged order tracks plus the offset, which never goes above
+	// the limit of a buy or below the limit of a sell
+	PegType   entities.PegType
+	PegOffset decimal.Decimal
+	PegLimit  decimal.Decimal
+
+	// Distance of a trailing stop from its watermark, the highest trade
+	// price since it was placed for a sell and the lowest for a buy. Zero
+	// watermark until there is a price to trail
+	TrailAmount  decimal.Decimal
+	TrailPercent decimal.Decimal
+	Watermark    decimal.Decimal
+
+	// Market order which trades until its funds are spent for a buy, or
+	// received for a sell, rather than until its size is filled. A market
+	// buy by size is bounded by its funds, and a market sell by funds by its
+	// size, which is what was held for them.
+	ByFunds bool
 }
 
 func newBookOrder(order *entities.Order) *BookOrder {
-	return &BookOrder{
-		OrderId: int64(order.ID),
-		Size:    order.Size,
-		Funds:   order.Funds,
-		Price:   order.Price,
-		Side:    order.Side,
-		Type:    order.Type,
+	bookOrder := &BookOrder{
+		OrderId:     int64(order.ID),
+		UserId:      int64(order.UserId),
+		Size:        order.Size,
+		TotalSize:   order.Size,
+		Funds:       order.Funds,
+		Price:       order.Price,
+		StopPrice:   order.StopPrice,
+		Side:        order.Side,
+		Type:        order.Type,
+		TimeInForce: order.TimeInForce,
+		PostOnly:    order.PostOnly,
+
+		SelfTradePrevention: order.SelfTradePrevention,
+
+		DisplaySize: order.DisplaySize,
+		Hidden:      order.Hidden,
+		Quote:       order.Quote,
+
+		TrailAmount:  order.TrailAmount,
+		TrailPercent: order.TrailPercent,
+	}
+	if order.Type == entities.MARKET_ORDER || order.Type == entities.STOP_MARKET_ORDER {
+		// A market buy is by funds unless it has a size, and a market sell
+		// by size unless it has funds
+		if order.Side == entities.SideBuy {
+			bookOrder.ByFunds = order.Size.IsZero()
+		} else {
+			bookOrder.ByFunds = order.Funds.GreaterThan(decimal.Zero)
+		}
+	}
+	if len(order.PegType) > 0 {
+		bookOrder.PegType = order.PegType
+		bookOrder.PegOffset = order.PegOffset
+		bookOrder.PegLimit = order.Price
+	}
+	if order.ExpireTime != nil {
+		bookOrder.ExpireTime = *order.ExpireTime
 	}
+	return bookOrder
+}
+
+// tracksSize reports whether the size of the order bounds what it trades, which
+// a market buy by funds has none of
+func (o *BookOrder) tracksSize() bool {
+	return !o.ByFunds || o.Side == entities.SideSell
+}
+
+// tracksFunds reports whether the funds of the order bound what it trades, which
+// is so for market buys and for market sells by funds
+func (o *BookOrder) tracksFunds() bool {
+	return o.Type == entities.MARKET_ORDER && (o.Side == entities.SideBuy || o.ByFunds)
+}
+
+func (o *BookOrder) isTrailing() bool {
+	return o.TrailAmount.GreaterThan(decimal.Zero) || o.TrailPercent.GreaterThan(decimal.Zero)
+}
+
+// trail moves the watermark of a trailing stop to the trade price if it's a
+// new high for a sell or a new low for a buy, and the stop price along with
+// it. It reports whether the stop price changed.
+func (o *BookOrder) trail(price decimal.Decimal) bool {
+	if price.LessThanOrEqual(decimal.Zero) {
+		return false
+	}
+	if !o.Watermark.IsZero() && ((o.Side == entities.SideSell && price.LessThanOrEqual(o.Watermark)) ||
+		(o.Side == entities.SideBuy && price.GreaterThanOrEqual(o.Watermark))) {
+		return false
+	}
+
+	o.Watermark = price
+	distance := o.TrailAmount
+	if o.TrailPercent.GreaterThan(decimal.Zero) {
+		distance = price.Mul(o.TrailPercent).Div(decimal.New(100, 0))
+	}
+	if o.Side == entities.SideSell {
+		o.StopPrice = decimal.Max(price.Sub(distance), decimal.Zero)
+	} else {
+		o.StopPrice = price.Add(distance)
+	}
+	return true
+}
+
+// isSelfTrade reports whether the taker is not allowed to trade with the maker.
+// Orders without a self-trade prevention mode, like the ones placed before it
+// existed, trade with anyone so that replaying them gives the same result.
+func (o *BookOrder) isSelfTrade(makerOrder *BookOrder) bool {
+	return len(o.SelfTradePrevention) > 0 && o.UserId != 0 && o.UserId == makerOrder.UserId
+}
+
+// visibleSize returns the size the order is able to trade before its next
+// slice is shown
+func (o *BookOrder) visibleSize() decimal.Decimal {
+	return o.Size.Sub(o.ReserveSize)
+}
+
+// displayedSize returns the size shown on the book
+func (o *BookOrder) displayedSize() decimal.Decimal {
+	if o.Hidden {
+		return decimal.Zero
+	}
+	return o.visibleSize()
 }
 
 func priceOrderIdKeyAscComparator(a, b interface{}) int {
 	aAsserted := a.(*priceOrderIdKey)
-	bAsserted := a.(*priceOrderIdKey)
+	bAsserted := b.(*priceOrderIdKey)
 
 	x := aAsserted.price.Cmp(bAsserted.price)
 	if x != 0 {
 		return x
 	}
+
+	if aAsserted.priority != bAsserted.priority {
+		if aAsserted.priority > bAsserted.priority {
+			return 1
+		}
+		return -1
+	}
+
 	y := aAsserted.orderId - bAsserted.orderId
 	if y == 0 {
 		return 0
@@ -331,6 +1823,13 @@ func priceOrderIdKeyDescComparator(a, b interface{}) int {
 		return -x
 	}
 
+	if aAsserted.priority != bAsserted.priority {
+		if aAsserted.priority > bAsserted.priority {
+			return 1
+		}
+		return -1
+	}
+
 	y := aAsserted.orderId - bAsserted.orderId
 	if y == 0 {
 		return 0
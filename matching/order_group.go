@@ -0,0 +1,297 @@
+package matching
+
+import (
+	"github.com/irononet/go-exchange/entities"
+	"github.com/shopspring/decimal"
+	"github.com/siddontang/go-log/log"
+)
+
+// orderGroup links orders of a user placed together. A trade or the end of a
+// leg cancels the other legs, and the end of a bracket entry arms its exits,
+// which are then linked the same way.
+type orderGroup struct {
+	GroupId int64
+	Type    entities.OrderGroupType
+
+	// Entry of a bracket until it's done, zero for an OCO group
+	EntryId int64
+
+	// Size the entry has filled, which the exits are armed for
+	EntryFilledSize decimal.Decimal
+
+	// Legs on the book or in the stop book
+	Legs []groupLeg
+
+	// Exits of a bracket waiting for the entry to be done
+	PendingLegs []BookOrder
+}
+
+type groupLeg struct {
+	OrderId int64
+	Side    entities.Side
+}
+
+func (g *orderGroup) hasLeg(orderId int64) bool {
+	for _, leg := range g.Legs {
+		if leg.OrderId == orderId {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *orderGroup) removeLeg(orderId int64) {
+	for i, leg := range g.Legs {
+		if leg.OrderId == orderId {
+			g.Legs = append(g.Legs[:i], g.Legs[i+1:]...)
+			return
+		}
+	}
+}
+
+// copy returns a group which shares none of its legs with g, the legs of the
+// book's groups change in place while a snapshot is being stored
+func (g *orderGroup) copy() orderGroup {
+	c := *g
+	c.Legs = append([]groupLeg(nil), g.Legs...)
+	c.PendingLegs = append([]BookOrder(nil), g.PendingLegs...)
+	return c
+}
+
+func (g *orderGroup) done() bool {
+	return g.EntryId == 0 && len(g.Legs) == 0 && len(g.PendingLegs) == 0
+}
+
+// ApplyOrderGroup applies the orders of a group in a single step. The legs of
+// an OCO group are applied one after the other, a leg left once another one has
+// traded or ended is cancelled right away. The exits of a bracket are received
+// along with the entry and wait for it to be done.
+func (o *OrderBook) ApplyOrderGroup(groupId int64, groupType entities.OrderGroupType, orders []*entities.Order) (logs []Log) {
+	_, found := o.groups[groupId]
+	if found || (len(orders) > 0 && o.orderIdWindow.contains(int64(orders[0].ID))) {
+		log.Warnf("order group %v skipped: already applied", groupId)
+		return logs
+	}
+
+	group := &orderGroup{GroupId: groupId, Type: groupType}
+	o.groups[groupId] = group
+
+	var entry *entities.Order
+	for _, order := range orders {
+		orderId := int64(order.ID)
+		o.legGroups[orderId] = groupId
+
+		switch order.GroupRole {
+		case entities.OrderGroupRoleEntry:
+			group.EntryId = orderId
+			entry = order
+		case entities.OrderGroupRoleTakeProfit, entities.OrderGroupRoleStopLoss:
+			if err := o.orderIdWindow.put(orderId); err != nil {
+				log.Error(err)
+				delete(o.legGroups, orderId)
+				continue
+			}
+			exit := newBookOrder(order)
+			group.PendingLegs = append(group.PendingLegs, *exit)
+			logs = append(logs, newReceivedLog(o.nextLogSeq(), int64(o.product.ID), exit))
+		default:
+			group.Legs = append(group.Legs, groupLeg{OrderId: orderId, Side: order.Side})
+		}
+	}
+
+	if entry != nil {
+		return append(logs, o.ApplyOrder(entry)...)
+	}
+
+	for _, order := range orders {
+		orderId := int64(order.ID)
+		if group.hasLeg(orderId) {
+			logs = append(logs, o.ApplyOrder(order)...)
+			continue
+		}
+
+		delete(o.legGroups, orderId)
+		if err := o.orderIdWindow.put(orderId); err != nil {
+			log.Error(err)
+			continue
+		}
+
+		leg := newBookOrder(order)
+		receivedLog := newReceivedLog(o.nextLogSeq(), int64(o.product.ID), leg)
+		doneLog := newDoneLog(o.nextLogSeq(), int64(o.product.ID), leg, leg.Size, entities.DoneReasonCancelled)
+		logs = append(logs, receivedLog, doneLog)
+	}
+	if group.done() {
+		delete(o.groups, groupId)
+	}
+	return logs
+}
+
+// applyGroupRules walks through the logs of a step and applies the rules of the
+// groups of the orders in them. What the rules bring about is appended to the
+// logs and walked through as well.
+func (o *OrderBook) applyGroupRules(logs []Log) []Log {
+	if len(o.legGroups) == 0 {
+		return logs
+	}
+
+	for i := 0; i < len(logs); i++ {
+		switch l := logs[i].(type) {
+		case *MatchLog:
+			logs = o.groupLegTraded(l.TakerOrderId, l.Size, logs)
+			logs = o.groupLegTraded(l.MakerOrderId, l.Size, logs)
+		case *DoneLog:
+			logs = o.groupLegDone(l.OrderId, logs)
+		case *RejectedLog:
+			if !l.Amend {
+				logs = o.groupLegDone(l.OrderId, logs)
+			}
+		}
+	}
+	return logs
+}
+
+func (o *OrderBook) groupLegTraded(orderId int64, size decimal.Decimal, logs []Log) []Log {
+	groupId, found := o.legGroups[orderId]
+	if !found {
+		return logs
+	}
+	group := o.groups[groupId]
+
+	if orderId == group.EntryId {
+		group.EntryFilledSize = group.EntryFilledSize.Add(size)
+		return logs
+	}
+	return o.cancelGroupLegs(group, orderId, logs)
+}
+
+func (o *OrderBook) groupLegDone(orderId int64, logs []Log) []Log {
+	groupId, found := o.legGroups[orderId]
+	if !found {
+		return logs
+	}
+	delete(o.legGroups, orderId)
+	group := o.groups[groupId]
+
+	if orderId == group.EntryId {
+		group.EntryId = 0
+		logs = o.armExits(group, logs)
+	} else {
+		group.removeLeg(orderId)
+		logs = o.cancelGroupLegs(group, 0, logs)
+	}
+
+	if group.done() {
+		delete(o.groups, groupId)
+	}
+	return logs
+}
+
+// cancelGroupLegs cancels the legs of the group other than keepOrderId, in the
+// order they were placed, and the exits waiting for a bracket entry
+func (o *OrderBook) cancelGroupLegs(group *orderGroup, keepOrderId int64, logs []Log) []Log {
+	for i := range group.PendingLegs {
+		exit := &group.PendingLegs[i]
+		delete(o.legGroups, exit.OrderId)
+		doneLog := newDoneLog(o.nextLogSeq(), int64(o.product.ID), exit, exit.Size, entities.DoneReasonCancelled)
+		logs = append(logs, doneLog)
+	}
+	group.PendingLegs = nil
+
+	var kept []groupLeg
+	for _, leg := range group.Legs {
+		if leg.OrderId == keepOrderId {
+			kept = append(kept, leg)
+			continue
+		}
+		delete(o.legGroups, leg.OrderId)
+		logs = o.cancelOrder(leg.OrderId, leg.Side, logs)
+	}
+	group.Legs = kept
+	return logs
+}
+
+// armExits puts the exits of a done bracket entry on the books, for no more
+// than the entry filled. The exits of an entry which filled nothing are
+// cancelled. An exit crossed by the market when it's armed trades right away.
+func (o *OrderBook) armExits(group *orderGroup, logs []Log) []Log {
+	exits := group.PendingLegs
+	group.PendingLegs = nil
+
+	if group.EntryFilledSize.IsZero() {
+		for i := range exits {
+			delete(o.legGroups, exits[i].OrderId)
+			doneLog := newDoneLog(o.nextLogSeq(), int64(o.product.ID), &exits[i], exits[i].Size, entities.DoneReasonCancelled)
+			logs = append(logs, doneLog)
+		}
+		return logs
+	}
+
+	for i := range exits {
+		exits[i].Size = decimal.Min(exits[i].Size, group.EntryFilledSize)
+		exits[i].TotalSize = exits[i].Size
+		group.Legs = append(group.Legs, groupLeg{OrderId: exits[i].OrderId, Side: exits[i].Side})
+	}
+
+	for i := range exits {
+		exit := &exits[i]
+		if !group.hasLeg(exit.OrderId) {
+			// cancelled by an exit armed before
+			delete(o.legGroups, exit.OrderId)
+			doneLog := newDoneLog(o.nextLogSeq(), int64(o.product.ID), exit, exit.Size, entities.DoneReasonCancelled)
+			logs = append(logs, doneLog)
+			continue
+		}
+
+		if !o.acceptsOrder(exit) {
+			doneLog := newDoneLog(o.nextLogSeq(), int64(o.product.ID), exit, exit.Size, entities.DoneReasonTradingStatus)
+			logs = append(logs, doneLog)
+			continue
+		}
+
+		if exit.Type.IsStop() {
+			o.stopBooks[exit.Side].add(*exit)
+			continue
+		}
+
+		if o.outsidePriceBand(exit) {
+			doneLog := newDoneLog(o.nextLogSeq(), int64(o.product.ID), exit, exit.Size, entities.DoneReasonPriceBand)
+			logs = append(logs, doneLog)
+			continue
+		}
+
+		from := len(logs)
+		logs = o.matchOrder(exit, logs)
+		logs = append(logs[:from:from], o.activateStopOrders(logs[from:])...)
+
+		// The rules of the group are applied to the trades of the exit
+		// before the next exit is armed
+		for j := from; j < len(logs); j++ {
+			if matchLog, ok := logs[j].(*MatchLog); ok && (matchLog.TakerOrderId == exit.OrderId || matchLog.MakerOrderId == exit.OrderId) {
+				logs = o.cancelGroupLegs(group, exit.OrderId, logs)
+				break
+			}
+		}
+	}
+	return logs
+}
+
+// cancelPendingLeg cancels an exit waiting for its bracket entry
+func (o *OrderBook) cancelPendingLeg(orderId int64, logs []Log) []Log {
+	groupId, found := o.legGroups[orderId]
+	if !found {
+		return logs
+	}
+	group := o.groups[groupId]
+
+	for i := range group.PendingLegs {
+		exit := group.PendingLegs[i]
+		if exit.OrderId != orderId {
+			continue
+		}
+		group.PendingLegs = append(group.PendingLegs[:i], group.PendingLegs[i+1:]...)
+		doneLog := newDoneLog(o.nextLogSeq(), int64(o.product.ID), &exit, exit.Size, entities.DoneReasonCancelled)
+		return append(logs, doneLog)
+	}
+	return logs
+}
@@ -0,0 +1,97 @@
+package matching
+
+import (
+	"github.com/emirpasic/gods/maps/treemap"
+	"github.com/irononet/go-exchange/entities"
+	"github.com/shopspring/decimal"
+)
+
+// stopBook keeps the untriggered stop orders of one side. They don't take
+// part in matching until a trade price crosses their stop price.
+type stopBook struct {
+	side entities.Side
+
+	// All untriggered orders
+	orders map[int64]*BookOrder
+
+	// stop price first, time first queue, the first order is the next one
+	// to be triggered. PriceOrderIdKey - orderId
+	queue *treemap.Map
+
+	// Trailing stops, whose stop price moves with the trade price. The ones
+	// without a watermark yet are kept out of the queue
+	trailing map[int64]*BookOrder
+}
+
+func newStopBook(side entities.Side) *stopBook {
+	// Buy stops trigger when the price rises to their stop price, so the lowest
+	// stop price comes first. Sell stops trigger on the way down.
+	comparator := priceOrderIdKeyAscComparator
+	if side == entities.SideSell {
+		comparator = priceOrderIdKeyDescComparator
+	}
+
+	return &stopBook{
+		side:   side,
+		orders: map[int64]*BookOrder{},
+		queue:  treemap.NewWith(comparator),
+
+		trailing: map[int64]*BookOrder{},
+	}
+}
+
+func (b *stopBook) add(order BookOrder) {
+	b.orders[order.OrderId] = &order
+	if order.isTrailing() {
+		b.trailing[order.OrderId] = &order
+		if order.Watermark.IsZero() {
+			return
+		}
+	}
+	b.queue.Put(&priceOrderIdKey{order.StopPrice, 0, order.OrderId}, order.OrderId)
+}
+
+func (b *stopBook) remove(orderId int64) (*BookOrder, bool) {
+	order, found := b.orders[orderId]
+	if !found {
+		return nil, false
+	}
+
+	delete(b.orders, orderId)
+	delete(b.trailing, orderId)
+	b.queue.Remove(&priceOrderIdKey{order.StopPrice, 0, order.OrderId})
+	return order, true
+}
+
+// trail moves the trailing stops with the trade price, which doesn't trigger
+// the ones it moves
+func (b *stopBook) trail(tradePrice decimal.Decimal) {
+	for _, order := range b.trailing {
+		oldStopPrice, queued := order.StopPrice, !order.Watermark.IsZero()
+		if !order.trail(tradePrice) {
+			continue
+		}
+		if queued {
+			b.queue.Remove(&priceOrderIdKey{oldStopPrice, 0, order.OrderId})
+		}
+		b.queue.Put(&priceOrderIdKey{order.StopPrice, 0, order.OrderId}, order.OrderId)
+	}
+}
+
+// popTriggered removes and returns the first order whose stop price is crossed
+// by the trade price, nil if there is none.
+func (b *stopBook) popTriggered(tradePrice decimal.Decimal) *BookOrder {
+	key, orderId := b.queue.Min()
+	if key == nil {
+		return nil
+	}
+
+	stopPrice := key.(*priceOrderIdKey).price
+	if (b.side == entities.SideBuy && tradePrice.LessThan(stopPrice)) ||
+		(b.side == entities.SideSell && tradePrice.GreaterThan(stopPrice)) {
+		return nil
+	}
+
+	order, _ := b.remove(orderId.(int64))
+	return order
+}
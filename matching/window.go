@@ -78,6 +78,8 @@ func (b Bitmap) Data(copy bool) []byte {
 	return dataOrCopy(b, copy)
 }
 
+// Window remembers the values put in it within the last Cap values up to Max,
+// the range (Min, Max]. A value above the range slides the window up to it.
 type Window struct {
 	Min    int64
 	Max    int64
@@ -85,6 +87,15 @@ type Window struct {
 	Bitmap Bitmap
 }
 
+var (
+	// errWindowExisted is returned for a value already put in the window
+	errWindowExisted = errors.New("existed val")
+
+	// errWindowExpired is returned for a value the window slid past, which
+	// it can't tell whether it was put or not
+	errWindowExpired = errors.New("expired val")
+)
+
 func newWindow(min, max int64) Window {
 	return Window{
 		Min:    min,
@@ -94,22 +105,98 @@ func newWindow(min, max int64) Window {
 	}
 }
 
-func (w Window) put(val int64) error {
+func (w *Window) put(val int64) error {
 	if val <= w.Min {
-		return errors.New(fmt.Sprintf("expired val %v, current Window [%v-%v]", val, w.Min, w.Max))
+		return fmt.Errorf("%w %v, current Window [%v-%v]", errWindowExpired, val, w.Min, w.Max)
 	} else if val > w.Max {
+		// The values slid out of the window free their bits for the new
+		// ones
 		delta := val - w.Max
+		if delta >= w.Cap {
+			for i := range w.Bitmap {
+				w.Bitmap[i] = 0
+			}
+		} else {
+			for v := w.Max + 1; v <= val; v++ {
+				w.Bitmap.Set(v%w.Cap, false)
+			}
+		}
 		w.Min += delta
 		w.Max += delta
 		w.Bitmap.Set(val%w.Cap, true)
 	} else if w.Bitmap.Get(val % w.Cap) {
-		return errors.New(fmt.Sprintf("existed val %v", val))
+		return fmt.Errorf("%w %v", errWindowExisted, val)
 	} else {
 		w.Bitmap.Set(val%w.Cap, true)
 	}
 	return nil
 }
 
-func (w Window) contains(val int64) bool {
-	return false
+func (w *Window) contains(val int64) bool {
+	return val > w.Min && val <= w.Max && w.Bitmap.Get(val%w.Cap)
+}
+
+// copy returns a copy of the window which doesn't share its bitmap
+func (w *Window) copy() Window {
+	c := *w
+	c.Bitmap = Bitmap(w.Bitmap.Data(true))
+	return c
+}
+
+// clientUuidWindow remembers the client uuids of the last Cap orders which had
+// one, so that an order reusing the client uuid of a recent order of the same
+// user is told apart
+type clientUuidWindow struct {
+	Cap int
+
+	// Oldest first
+	Keys []clientUuidKey
+
+	index map[clientUuidKey]struct{}
+}
+
+type clientUuidKey struct {
+	UserId     int64
+	ClientUuid string
+}
+
+func newClientUuidWindow(cap int) clientUuidWindow {
+	return clientUuidWindow{Cap: cap, index: map[clientUuidKey]struct{}{}}
+}
+
+// put records the client uuid of an order of the user, forgetting the oldest
+// one once the window is full. It fails if the user already has it.
+func (w *clientUuidWindow) put(userId int64, clientUuid string) error {
+	key := clientUuidKey{UserId: userId, ClientUuid: clientUuid}
+	if _, found := w.index[key]; found {
+		return fmt.Errorf("%w client uuid %v of user %v", errWindowExisted, clientUuid, userId)
+	}
+
+	if len(w.Keys) >= w.Cap {
+		delete(w.index, w.Keys[0])
+		w.Keys = w.Keys[1:]
+	}
+	w.Keys = append(w.Keys, key)
+	w.index[key] = struct{}{}
+	return nil
+}
+
+func (w *clientUuidWindow) contains(userId int64, clientUuid string) bool {
+	_, found := w.index[clientUuidKey{UserId: userId, ClientUuid: clientUuid}]
+	return found
+}
+
+// copy returns a copy of the window which doesn't share its keys
+func (w *clientUuidWindow) copy() clientUuidWindow {
+	c := clientUuidWindow{Cap: w.Cap, Keys: make([]clientUuidKey, len(w.Keys))}
+	copy(c.Keys, w.Keys)
+	return c
+}
+
+// restore rebuilds the index of a window decoded from a snapshot
+func (w *clientUuidWindow) restore() {
+	w.index = map[clientUuidKey]struct{}{}
+	for _, key := range w.Keys {
+		w.index[key] = struct{}{}
+	}
 }
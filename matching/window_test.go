@@ -0,0 +1,286 @@
+package matching
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/irononet/go-exchange/entities"
+)
+
+func TestWindowPut(t *testing.T) {
+	tests := []struct {
+		name    string
+		puts    []int64
+		val     int64
+		wantErr error
+		wantMin int64
+		wantMax int64
+	}{
+		{name: "new value in range", puts: []int64{1, 2}, val: 3, wantMin: 0, wantMax: 10},
+		{name: "existing value", puts: []int64{1, 2, 3}, val: 2, wantErr: errWindowExisted, wantMin: 0, wantMax: 10},
+		{name: "value at min is expired", puts: []int64{12}, val: 2, wantErr: errWindowExpired, wantMin: 2, wantMax: 12},
+		{name: "value below min is expired", puts: []int64{15}, val: 1, wantErr: errWindowExpired, wantMin: 5, wantMax: 15},
+		{name: "new max slides the window", puts: []int64{1, 9}, val: 13, wantMin: 3, wantMax: 13},
+		{name: "new max beyond cap clears the window", puts: []int64{1, 9}, val: 40, wantMin: 30, wantMax: 40},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newWindow(0, 10)
+			for _, val := range tt.puts {
+				if err := w.put(val); err != nil {
+					t.Fatalf("put(%v): %v", val, err)
+				}
+			}
+
+			err := w.put(tt.val)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("put(%v) = %v, want %v", tt.val, err, tt.wantErr)
+			}
+			if w.Min != tt.wantMin || w.Max != tt.wantMax {
+				t.Fatalf("window (%v, %v], want (%v, %v]", w.Min, w.Max, tt.wantMin, tt.wantMax)
+			}
+			if tt.wantErr == nil && !w.contains(tt.val) {
+				t.Fatalf("window doesn't contain %v", tt.val)
+			}
+		})
+	}
+}
+
+func TestWindowSlideFreesBits(t *testing.T) {
+	tests := []struct {
+		name     string
+		puts     []int64
+		wantIn   []int64
+		wantOut  []int64
+		freshVal int64
+	}{
+		// 3 and 13 share a bit, the slide to 13 frees it for 13 only
+		{name: "slide within cap", puts: []int64{3, 8, 13}, wantIn: []int64{8, 13}, wantOut: []int64{3, 9, 12}, freshVal: 12},
+		{name: "slide beyond cap", puts: []int64{3, 8, 35}, wantIn: []int64{35}, wantOut: []int64{3, 8, 28, 33}, freshVal: 28},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newWindow(0, 10)
+			for _, val := range tt.puts {
+				if err := w.put(val); err != nil {
+					t.Fatalf("put(%v): %v", val, err)
+				}
+			}
+
+			for _, val := range tt.wantIn {
+				if !w.contains(val) {
+					t.Errorf("window doesn't contain %v", val)
+				}
+			}
+			for _, val := range tt.wantOut {
+				if w.contains(val) {
+					t.Errorf("window contains %v", val)
+				}
+			}
+			if err := w.put(tt.freshVal); err != nil {
+				t.Errorf("put(%v): %v", tt.freshVal, err)
+			}
+		})
+	}
+}
+
+func TestClientUuidWindowPut(t *testing.T) {
+	type put struct {
+		userId     int64
+		clientUuid string
+	}
+
+	tests := []struct {
+		name    string
+		cap     int
+		puts    []put
+		put     put
+		wantErr error
+		wantIn  []put
+		wantOut []put
+	}{
+		{
+			name:   "new client uuid",
+			cap:    3,
+			puts:   []put{{1, "a"}},
+			put:    put{1, "b"},
+			wantIn: []put{{1, "a"}, {1, "b"}},
+		},
+		{
+			name:    "existing client uuid of the user",
+			cap:     3,
+			puts:    []put{{1, "a"}},
+			put:     put{1, "a"},
+			wantErr: errWindowExisted,
+			wantIn:  []put{{1, "a"}},
+		},
+		{
+			name:    "same client uuid of another user",
+			cap:     3,
+			puts:    []put{{1, "a"}},
+			put:     put{2, "a"},
+			wantIn:  []put{{1, "a"}, {2, "a"}},
+			wantOut: []put{{3, "a"}},
+		},
+		{
+			name:    "full window evicts the oldest",
+			cap:     2,
+			puts:    []put{{1, "a"}, {1, "b"}},
+			put:     put{1, "c"},
+			wantIn:  []put{{1, "b"}, {1, "c"}},
+			wantOut: []put{{1, "a"}},
+		},
+		{
+			name:    "evicted client uuid can be used again",
+			cap:     2,
+			puts:    []put{{1, "a"}, {1, "b"}, {1, "c"}},
+			put:     put{1, "a"},
+			wantIn:  []put{{1, "c"}, {1, "a"}},
+			wantOut: []put{{1, "b"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newClientUuidWindow(tt.cap)
+			for _, p := range tt.puts {
+				if err := w.put(p.userId, p.clientUuid); err != nil {
+					t.Fatalf("put(%v, %v): %v", p.userId, p.clientUuid, err)
+				}
+			}
+
+			err := w.put(tt.put.userId, tt.put.clientUuid)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("put(%v, %v) = %v, want %v", tt.put.userId, tt.put.clientUuid, err, tt.wantErr)
+			}
+			if len(w.Keys) > tt.cap {
+				t.Fatalf("window holds %v keys, cap %v", len(w.Keys), tt.cap)
+			}
+			for _, p := range tt.wantIn {
+				if !w.contains(p.userId, p.clientUuid) {
+					t.Errorf("window doesn't contain %v of user %v", p.clientUuid, p.userId)
+				}
+			}
+			for _, p := range tt.wantOut {
+				if w.contains(p.userId, p.clientUuid) {
+					t.Errorf("window contains %v of user %v", p.clientUuid, p.userId)
+				}
+			}
+		})
+	}
+}
+
+func TestWindowCopy(t *testing.T) {
+	w := newWindow(0, 10)
+	_ = w.put(1)
+	c := w.copy()
+	_ = w.put(2)
+	if c.contains(2) {
+		t.Fatal("copy shares the bitmap of the window")
+	}
+
+	u := newClientUuidWindow(2)
+	_ = u.put(1, "a")
+	cu := u.copy()
+	cu.restore()
+	_ = u.put(1, "b")
+	if cu.contains(1, "b") || len(cu.Keys) != 1 {
+		t.Fatal("copy shares the keys of the window")
+	}
+}
+
+func TestWindowSnapshotRestore(t *testing.T) {
+	product := &entities.Product{BaseScale: 4, QuoteScale: 2}
+
+	tests := []struct {
+		name        string
+		orderIds    []int64
+		clientUuids []string
+		wantIds     []int64
+		wantNotIds  []int64
+		wantMin     int64
+	}{
+		{
+			name:       "empty windows",
+			wantNotIds: []int64{1},
+		},
+		{
+			name:        "ids and client uuids",
+			orderIds:    []int64{1, 2, 5},
+			clientUuids: []string{"a", "b"},
+			wantIds:     []int64{1, 2, 5},
+			wantNotIds:  []int64{3, 4},
+		},
+		{
+			name:       "slid window",
+			orderIds:   []int64{3, orderIdWindowCap + 7},
+			wantIds:    []int64{orderIdWindowCap + 7},
+			wantNotIds: []int64{3, 8},
+			wantMin:    7,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orderBook := NewOrderBook(product)
+			for _, id := range tt.orderIds {
+				if err := orderBook.orderIdWindow.put(id); err != nil {
+					t.Fatalf("put(%v): %v", id, err)
+				}
+			}
+			for _, clientUuid := range tt.clientUuids {
+				if err := orderBook.clientUuidWindow.put(1, clientUuid); err != nil {
+					t.Fatalf("put(%v): %v", clientUuid, err)
+				}
+			}
+
+			// The snapshot is stored as json
+			snapshot := orderBook.Snapshot()
+			buf, err := json.Marshal(snapshot)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var decoded orderBooSnapShot
+			if err = json.Unmarshal(buf, &decoded); err != nil {
+				t.Fatal(err)
+			}
+
+			restored := NewOrderBook(product)
+			restored.Restore(&decoded)
+
+			if restored.orderIdWindow.Min != tt.wantMin {
+				t.Errorf("restored min %v, want %v", restored.orderIdWindow.Min, tt.wantMin)
+			}
+			for _, id := range tt.wantIds {
+				if !restored.orderIdWindow.contains(id) {
+					t.Errorf("restored window doesn't contain %v", id)
+				}
+				if err := restored.orderIdWindow.put(id); !errors.Is(err, errWindowExisted) {
+					t.Errorf("put(%v) after restore = %v, want %v", id, err, errWindowExisted)
+				}
+			}
+			for _, id := range tt.wantNotIds {
+				if restored.orderIdWindow.contains(id) {
+					t.Errorf("restored window contains %v", id)
+				}
+			}
+			for _, clientUuid := range tt.clientUuids {
+				if err := restored.clientUuidWindow.put(1, clientUuid); !errors.Is(err, errWindowExisted) {
+					t.Errorf("put(%v) after restore = %v, want %v", clientUuid, err, errWindowExisted)
+				}
+			}
+			if restored.clientUuidWindow.Cap != orderIdWindowCap {
+				t.Errorf("restored client uuid cap %v, want %v", restored.clientUuidWindow.Cap, orderIdWindowCap)
+			}
+
+			// Changing the book after the snapshot leaves the snapshot alone
+			_ = orderBook.orderIdWindow.put(4)
+			if snapshot.OrderIdWindow.contains(4) {
+				t.Error("snapshot shares the bitmap of the book")
+			}
+		})
+	}
+}
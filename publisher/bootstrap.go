@@ -2,13 +2,25 @@ package publisher
 
 import (
 	"strconv"
+	"time"
 
 	"github.com/irononet/go-exchange/conf"
+	"github.com/irononet/go-exchange/entities"
 	"github.com/irononet/go-exchange/matching"
 	"github.com/irononet/go-exchange/service"
 	"github.com/siddontang/go-log/log"
 )
 
+// how often the products are checked for listings and delistings
+const productWatchInterval = 10 * time.Second
+
+// productStreams are the streams which publish the logs of a product
+type productStreams struct{
+	tickerStream *TickerStream 
+	matchStream *MatchStream 
+	orderBookStream *OrderBookStream
+}
+
 func StartServer(){
 	gexConfig := conf.GetConfig() 
 
@@ -16,19 +28,34 @@ func StartServer(){
 
 	NewRedisStream(sub).Start() 
 
-	products, err := service.GetProducts() 
-	if err != nil{
-		panic(err)
-	}
+	streams := map[string]*productStreams{}
 
-	for _, product := range products{
+	service.WatchProducts(productWatchInterval, func(product *entities.Product){
 		productIdStr := strconv.Itoa(int(product.ID))
-		NewTickerStream(productIdStr, sub, matching.NewKafkaLogReader("tickerStream", productIdStr, gexConfig.Kafka.Brokers)).Start() 
-		NewMatchStream(productIdStr, sub, matching.NewKafkaLogReader("matchStream", productIdStr, gexConfig.Kafka.Brokers)).Start() 
-		NewOrderBookStream(productIdStr, sub, matching.NewKafkaLogReader("orderBookStream", productIdStr, gexConfig.Kafka.Brokers)).Start() 
+		s := &productStreams{
+			tickerStream: NewTickerStream(productIdStr, sub, matching.NewKafkaLogReader("tickerStream", productIdStr, gexConfig.Kafka.Brokers)), 
+			matchStream: NewMatchStream(productIdStr, sub, matching.NewKafkaLogReader("matchStream", productIdStr, gexConfig.Kafka.Brokers)), 
+			orderBookStream: NewOrderBookStream(productIdStr, sub, matching.NewKafkaLogReader("orderBookStream", productIdStr, gexConfig.Kafka.Brokers)),
+		}
+		s.tickerStream.Start() 
+		s.matchStream.Start() 
+		s.orderBookStream.Start() 
+		streams[productIdStr] = s 
+
+		log.Infof("streams of %v started", productIdStr)
+	}, func(product *entities.Product){
+		productIdStr := strconv.Itoa(int(product.ID))
+		if s, found := streams[productIdStr]; found{
+			s.tickerStream.Stop() 
+			s.matchStream.Stop() 
+			s.orderBookStream.Stop() 
+			delete(streams, productIdStr)
+		}
+
+		log.Infof("streams of %v stopped", productIdStr)
+	})
 
-		go NewServer(gexConfig.PushServer.Addr, gexConfig.PushServer.Path, sub).Run() 
+	go NewServer(gexConfig.PushServer.Addr, gexConfig.PushServer.Path, sub).Run() 
 
-		log.Info("websocket server ok")
-	}
-}
\ No newline at end of file
+	log.Info("websocket server ok")
+}
@@ -3,12 +3,16 @@ package publisher
 import (
 	"context"
 	"encoding/json"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/irononet/go-exchange/entities"
+	"github.com/irononet/go-exchange/matching"
 	"github.com/irononet/go-exchange/service"
+	"github.com/shopspring/decimal"
 	"github.com/siddontang/go-log/log"
 )
 
@@ -16,7 +20,7 @@ const (
 	WRITE_WAIT       = 10 * time.Second
 	PONG_WAIT        = 60 * time.Second
 	PING_PERIOD      = (PONG_WAIT * 9) / 10
-	MAX_MESSAGE_SIZE = 512
+	MAX_MESSAGE_SIZE = 16 * 1024
 )
 
 var Id int64
@@ -219,6 +223,10 @@ func (c *Client) OnMessage(req *Request) {
 		c.OnSub(req.CurrencyIds, req.ProductIds, req.Channels, req.Token)
 	case "unsubscribe":
 		c.OnUnSub(req.CurrencyIds, req.ProductIds, req.Channels, req.Token)
+	case "cancel_all":
+		c.OnCancelAll(req.ProductIds, req.Side, req.Token)
+	case "mass_quote":
+		c.OnMassQuote(req.ProductId, req.Quotes, req.PostOnly, req.Token)
 	default:
 	}
 }
@@ -256,6 +264,25 @@ func (c *Client) OnSub(currencyIds []string, productIds []string, channels []str
 			case CHANNEL_MATCH:
 				c.Subscribe(CHANNEL_MATCH.FormatWithProductId(productId))
 
+			case CHANNEL_FULL:
+				c.Subscribe(CHANNEL_FULL.FormatWithProductId(productId))
+
+			case CHANNEL_STATUS:
+				if c.Subscribe(CHANNEL_STATUS.FormatWithProductId(productId)) {
+					// the status last published, or else the one last sent
+					// to the engine, the changes follow
+					if status := getLastStatus(productId); status != nil {
+						c.WriteCh <- status
+						break
+					}
+					product, err := service.GetProductById(productId)
+					if err != nil {
+						log.Error(err)
+					} else if product != nil {
+						c.WriteCh <- &StatusMessage{Type: "status", ProductId: productId, Status: string(product.GetTradingStatus())}
+					}
+				}
+
 			case CHANNEL_TICKER:
 				if c.Subscribe(CHANNEL_TICKER.FormatWithProductId(productId)) {
 					ticker := getLastTicker(productId)
@@ -301,6 +328,10 @@ func (c *Client) OnUnSub(currencyIds []string, productIds []string, channels []s
 				c.Unsubscribe(CHANNEL_LEVEL_2.FormatWithProductId(productId))
 			case CHANNEL_MATCH:
 				c.Unsubscribe(CHANNEL_LEVEL_2.FormatWithProductId(productId))
+			case CHANNEL_FULL:
+				c.Unsubscribe(CHANNEL_FULL.FormatWithProductId(productId))
+			case CHANNEL_STATUS:
+				c.Unsubscribe(CHANNEL_STATUS.FormatWithProductId(productId))
 			case CHANNEL_TICKER:
 				c.Unsubscribe(CHANNEL_TICKER.FormatWithProductId(productId))
 			case CHANNEL_ORDER:
@@ -313,6 +344,93 @@ func (c *Client) OnUnSub(currencyIds []string, productIds []string, channels []s
 	}
 }
 
+// OnCancelAll asks the matching engines to cancel every order of the user, on
+// every product if productIds is empty
+func (c *Client) OnCancelAll(productIds []string, rawSide string, token string) {
+	user, err := service.CheckToken(token)
+	if err != nil || user == nil {
+		c.WriteCh <- &ErrorMessage{Type: "error", Message: "cancel_all requires a valid token"}
+		return
+	}
+
+	var side *entities.Side
+	if len(rawSide) > 0 {
+		side, err = entities.NewSideFromString(rawSide)
+		if err != nil {
+			c.WriteCh <- &ErrorMessage{Type: "error", Message: err.Error()}
+			return
+		}
+	}
+
+	if len(productIds) == 0 {
+		products, err := service.GetProducts()
+		if err != nil {
+			log.Error(err)
+			c.WriteCh <- &ErrorMessage{Type: "error", Message: "cancel_all failed"}
+			return
+		}
+
+		for _, product := range products {
+			productIds = append(productIds, strconv.Itoa(int(product.ID)))
+		}
+	}
+
+	for _, productId := range productIds {
+		err = matching.SharedCommandWriter(productId).Write(matching.NewMassCancelCommand(int64(user.ID), side))
+		if err != nil {
+			log.Error(err)
+			c.WriteCh <- &ErrorMessage{Type: "error", Message: "cancel_all failed"}
+			return
+		}
+	}
+
+	c.WriteCh <- &Response{Type: "cancel_all", ProductIds: productIds}
+}
+
+// OnMassQuote replaces every quote of the user on the product with the quotes of
+// the request in a single step of the matching engine
+func (c *Client) OnMassQuote(productId string, quotes []*QuoteRequest, postOnly bool, token string) {
+	user, err := service.CheckToken(token)
+	if err != nil || user == nil {
+		c.WriteCh <- &ErrorMessage{Type: "error", Message: "mass_quote requires a valid token"}
+		return
+	}
+
+	var levels []service.QuoteLevel
+	for _, quote := range quotes {
+		price, err := decimal.NewFromString(quote.Price)
+		if err != nil {
+			c.WriteCh <- &ErrorMessage{Type: "error", Message: "invalid quote price: " + quote.Price}
+			return
+		}
+		size, err := decimal.NewFromString(quote.Size)
+		if err != nil {
+			c.WriteCh <- &ErrorMessage{Type: "error", Message: "invalid quote size: " + quote.Size}
+			return
+		}
+		levels = append(levels, service.QuoteLevel{Side: entities.Side(quote.Side), Price: price, Size: size})
+	}
+
+	orders, err := service.PlaceMassQuote(int64(user.ID), productId, levels, postOnly)
+	if err != nil {
+		c.WriteCh <- &ErrorMessage{Type: "error", Message: err.Error()}
+		return
+	}
+
+	err = matching.SharedCommandWriter(productId).Write(matching.NewMassQuoteCommand(int64(user.ID), orders))
+	if err != nil {
+		log.Error(err)
+		c.WriteCh <- &ErrorMessage{Type: "error", Message: "mass_quote failed"}
+		return
+	}
+
+	var orderIds []string
+	for _, order := range orders {
+		orderIds = append(orderIds, strconv.Itoa(int(order.ID)))
+	}
+	c.WriteCh <- &Response{Type: "mass_quote", ProductIds: []string{productId}, OrderIds: orderIds}
+}
+
 func (c *Client) Subscribe(channel string) bool {
 	c.Mu.Lock()
 	defer c.Mu.Unlock()
@@ -5,9 +5,14 @@ import (
 	"github.com/irononet/go-exchange/entities" 
 	"github.com/irononet/go-exchange/utils" 
 	"github.com/shopspring/decimal" 
+	"sync"
 	"time"
 )
 
+// Last status published for each product, which may be ahead of the product
+// table until the status log is recorded
+var lastStatuses sync.Map
+
 type MatchStream struct{
 	ProductId string 
 	Sub *Subscription 
@@ -34,6 +39,14 @@ func (s *MatchStream) Start(){
 	go s.LogReader.Run(0, -1)
 }
 
+func (s *MatchStream) Stop(){
+	s.LogReader.Stop()
+}
+
+func (s *MatchStream) OnReceivedLog(log *matching.ReceivedLog, offset int64){
+	// do nothing
+}
+
 func (s *MatchStream) OnOpenLOg(log *matching.OpenLog, offset int64){
 	// do nothing
 }
@@ -42,14 +55,52 @@ func (s *MatchStream) OnDoneLog(log *matching.DoneLog, offset int64){
 	// do nothing
 }
 
+func (s *MatchStream) OnActivateLog(log *matching.ActivateLog, offset int64){
+	// do nothing
+}
+
+func (s *MatchStream) OnChangeLog(log *matching.ChangeLog, offset int64){
+	// do nothing
+}
+
+func (s *MatchStream) OnAmendLog(log *matching.AmendLog, offset int64){
+	// do nothing
+}
+
+func (s *MatchStream) OnRejectedLog(log *matching.RejectedLog, offset int64){
+	// do nothing
+}
+
+func (s *MatchStream) OnStatusLog(log *matching.StatusLog, offset int64){
+	status := &StatusMessage{
+		Type: "status", 
+		Sequence: log.Sequence, 
+		Time: log.Time.Format(time.RFC3339), 
+		ProductId: s.ProductId, 
+		Status: string(log.Status), 
+		OldStatus: string(log.OldStatus),
+		Reason: string(log.Reason),
+	}
+	lastStatuses.Store(s.ProductId, status)
+	s.Sub.Publish(CHANNEL_STATUS.FormatWithProductId(s.ProductId), status)
+}
+
+func getLastStatus(productId string) *StatusMessage{
+	status, found := lastStatuses.Load(productId) 
+	if !found{
+		return nil 
+	}
+	return status.(*StatusMessage)
+}
+
 func (s *MatchStream) OnMatchLog(log *matching.MatchLog, offset int64){
 	// push match 
-	s.Sub.Publish(string(CHANNEL_MATCH.FormatWithProductId(string(log.ProductId))), &MatchMessage{
+	s.Sub.Publish(string(CHANNEL_MATCH.FormatWithProductId(s.ProductId)), &MatchMessage{
 		Type: "match", 
 		TradeId: log.TradeId, 
 		Sequence: log.Sequence, 
 		Time: log.Time.Format(time.RFC3339), 
-		ProductId: string(log.ProductId),
+		ProductId: s.ProductId,
 		Price: log.Price.String(), 
 		Side: log.Side.String(), 
 		MakerOrderId: utils.I64ToA(log.MakerOrderId), 
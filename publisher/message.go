@@ -26,6 +26,12 @@ const (
 	CHANNEL_LEVEL_2 = Channel("level2") 
 	CHANNEL_FUNDS = Channel("funds") 
 	CHANNEL_ORDER = Channel("order")
+
+	// level3, every event of the orders shown on the book
+	CHANNEL_FULL = Channel("full")
+
+	// trading status of the product
+	CHANNEL_STATUS = Channel("status")
 )
 
 
@@ -33,8 +39,21 @@ type Request struct{
 	Type	string `json:"type"` 
 	ProductIds []string `json:"product_ids"` 
 	CurrencyIds []string `json:"currency_ids"` 
-	Channels []string `json"channels"` 
+	Channels []string `json:"channels"` 
 	Token string `json:"token"` 
+	Side string `json:"side"` 
+
+	// Quotes of a mass_quote request, which replace every quote of the
+	// user on the product
+	ProductId string `json:"product_id"` 
+	PostOnly bool `json:"post_only"` 
+	Quotes []*QuoteRequest `json:"quotes"` 
+}
+
+type QuoteRequest struct{
+	Side string `json:"side"` 
+	Price string `json:"price"` 
+	Size string `json:"size"` 
 }
 
 type Response struct{
@@ -42,6 +61,12 @@ type Response struct{
 	ProductIds []string `json:"product_ids"` 
 	Channels []string `json:"channels"` 
 	Token string `json:"token"` 
+	OrderIds []string `json:"order_ids,omitempty"` 
+}
+
+type ErrorMessage struct{
+	Type string `json:"type"` 
+	Message string `json:"message"` 
 }
 
 type Level2SnapshotMessage struct{
@@ -92,9 +117,45 @@ type TickerMessage struct{
 	Volume24h string `json:"volume24h"`  
 	Volume30d string `json:"volume30d"` 
 	Low24h string `json:"low24h"` 
+	High24h string `json:"high24h"` 
 	Open24h string `json:"open24h"`
 }
 
+// FullMessage is an event of the full channel. Hidden orders and the reserve of
+// iceberg orders are never sent, and done is only sent for the orders which
+// were open on the book
+type FullMessage struct{
+	Type string `json:"type"` // received, open, done, match, change, activate 
+	Sequence int64 `json:"sequence"` 
+	Time string `json:"time"` 
+	ProductId string `json:"productId"` 
+	OrderId string `json:"orderId,omitempty"` 
+	OrderType string `json:"orderType,omitempty"` 
+	Side string `json:"side"` 
+	Price string `json:"price,omitempty"` 
+	StopPrice string `json:"stopPrice,omitempty"` 
+	Size string `json:"size,omitempty"` 
+	Funds string `json:"funds,omitempty"` 
+	RemainingSize string `json:"remainingSize,omitempty"` 
+	Reason string `json:"reason,omitempty"` 
+	NewSize string `json:"newSize,omitempty"` 
+	OldSize string `json:"oldSize,omitempty"` 
+	NewPrice string `json:"newPrice,omitempty"` 
+	TradeId int64 `json:"tradeId,omitempty"` 
+	MakerOrderId string `json:"makerOrderId,omitempty"` 
+	TakerOrderId string `json:"takerOrderId,omitempty"` 
+}
+
+type StatusMessage struct{
+	Type string `json:"type"` 
+	Sequence int64 `json:"sequence"` 
+	Time string `json:"time"` 
+	ProductId string `json:"productId"` 
+	Status string `json:"status"` 
+	OldStatus string `json:"oldStatus,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
 type FundsMessage struct{
 	Type string `json:"type"` 
 	Sequence int64 `json:"sequence"` 
@@ -121,4 +182,5 @@ type OrderMessage struct{
 	ExecutedValue string `json:"executedValue"` 
 	Status string `json:"status"` 
 	Settled bool `json:"settled"`
+	RejectReason string `json:"rejectReason,omitempty"` 
 }
\ No newline at end of file
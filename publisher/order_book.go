@@ -188,7 +188,9 @@ type RedisSnapshotStore struct{
 var store *RedisSnapshotStore 
 var onceStore sync.Once 
 
-func sharedSnapshotStore() *RedisSnapshotStore{
+// SharedSnapshotStore returns the store the order book snapshots are saved to,
+// also read by the rest api to serve the order book
+func SharedSnapshotStore() *RedisSnapshotStore{
 	onceStore.Do(func() {
 		gexConfig := conf.GetConfig() 
 
@@ -1,8 +1,10 @@
 package publisher 
 
 import (
-	"fmt" 
+	"github.com/irononet/go-exchange/entities" 
 	"github.com/irononet/go-exchange/matching" 
+	"github.com/irononet/go-exchange/utils" 
+	"github.com/shopspring/decimal" 
 	logger "github.com/siddontang/go-log/log" 
 	"sync" 
 	"time"
@@ -15,6 +17,7 @@ type OrderBookStream struct{
 	OrderBook *OrderBook 
 	Sub *Subscription 
 	SnapshotCh chan interface{}
+	stopCh chan struct{}
 }
 
 type LogOffset struct{
@@ -30,10 +33,11 @@ func NewOrderBookStream(productId string, sub *Subscription, logReader matching.
 		Sub: sub, 
 		LogReader: logReader, 
 		SnapshotCh: make(chan interface{}, 100),
+		stopCh: make(chan struct{}),
 	}
 
 	// try to restore snapshot 
-	snapshot, err := sharedSnapshotStore().GetLastFull(productId) 
+	snapshot, err := SharedSnapshotStore().GetLastFull(productId) 
 	if err != nil{
 		logger.Fatalf("get snaphost error: %v", err) 
 	}
@@ -57,6 +61,15 @@ func (s *OrderBookStream) Start(){
 	go s.runSnapshots()
 }
 
+func (s *OrderBookStream) Stop(){
+	s.LogReader.Stop() 
+	close(s.stopCh)
+}
+
+func (s *OrderBookStream) OnReceivedLog(log *matching.ReceivedLog, offset int64){
+	s.LogCh <- &LogOffset{log, offset}
+}
+
 func (s *OrderBookStream) OnOpenLOg(log *matching.OpenLog, offset int64){
 	s.LogCh <- &LogOffset{log, offset} 
 }
@@ -69,7 +82,27 @@ func (s *OrderBookStream) OnDoneLog(log *matching.DoneLog, offset int64){
 	s.LogCh <- &LogOffset{log, offset}
 }
 
-var lastLevel2Snapshots *sync.Map
+func (s *OrderBookStream) OnActivateLog(log *matching.ActivateLog, offset int64){
+	s.LogCh <- &LogOffset{log, offset}
+}
+
+func (s *OrderBookStream) OnChangeLog(log *matching.ChangeLog, offset int64){
+	s.LogCh <- &LogOffset{log, offset}
+}
+
+func (s *OrderBookStream) OnAmendLog(log *matching.AmendLog, offset int64){
+	s.LogCh <- &LogOffset{log, offset}
+}
+
+func (s *OrderBookStream) OnStatusLog(log *matching.StatusLog, offset int64){
+	s.LogCh <- &LogOffset{log, offset}
+}
+
+func (s *OrderBookStream) OnRejectedLog(log *matching.RejectedLog, offset int64){
+	s.LogCh <- &LogOffset{log, offset}
+}
+
+var lastLevel2Snapshots sync.Map
 
 func (s *OrderBookStream) runApplier(){
 	var lastLevel2Snapshot *OrderBookLevel2Snapshot 
@@ -77,73 +110,242 @@ func (s *OrderBookStream) runApplier(){
 
 	for{
 		select{
+		case <- s.stopCh: 
+			return 
+
 		case logOffset := <- s.LogCh: 
 			var l2Change *Level2Change 
+			var fullMessage *FullMessage 
 
 			switch logOffset.Log.(type){
+			case *matching.ReceivedLog: 
+				// the order shows up on the book with the open log that
+				// follows, only keep track of the position
+				log := logOffset.Log.(*matching.ReceivedLog) 
+				s.OrderBook.LogOffset = logOffset.Offset 
+				s.OrderBook.LogSeq = log.Sequence 
+
+				if !log.Hidden{
+					fullMessage = s.newFullMessage("received", log.Base, log.OrderId, log.Side.String()) 
+					fullMessage.OrderType = log.OrderType.String() 
+					if log.Price.GreaterThan(decimal.Zero){
+						fullMessage.Price = log.Price.String() 
+					}
+					if log.StopPrice.GreaterThan(decimal.Zero){
+						fullMessage.StopPrice = log.StopPrice.String() 
+					}
+					if log.DisplaySize.GreaterThan(decimal.Zero){
+						fullMessage.Size = log.DisplaySize.String() 
+					} else if log.Size.GreaterThan(decimal.Zero){
+						fullMessage.Size = log.Size.String() 
+					}
+					if log.OrderType == entities.MARKET_ORDER && log.Funds.GreaterThan(decimal.Zero){
+						fullMessage.Funds = log.Funds.String() 
+					}
+				}
+
 			case *matching.DoneLog: 
 				log := logOffset.Log.(*matching.DoneLog) 
 				order, found := s.OrderBook.Orders[log.OrderId] 
+
+				// every order received is done, whether it ever rested on
+				// the book or not
+				if !log.Hidden{
+					fullMessage = s.newFullMessage("done", log.Base, log.OrderId, log.Side.String()) 
+					fullMessage.Price = log.Price.String() 
+					fullMessage.RemainingSize = log.RemainingSize.String() 
+					fullMessage.Reason = string(log.Reason) 
+				}
+
 				if !found{
-					continue 
+					// never on the book, only keep track of the position
+					s.OrderBook.LogOffset = logOffset.Offset 
+					s.OrderBook.LogSeq = log.Sequence 
+					break 
 				}
-				newSize := order.Size.Sub(log.RemainingSize) 
-				l2Change = s.OrderBook.SaveOrder(logOffset.Offset, log.Sequence, log.OrderId, newSize, log.Price, log.Side) 
+				if fullMessage != nil{
+					// the remaining size includes the reserve of an iceberg
+					// order, which was never shown
+					fullMessage.RemainingSize = order.Size.String() 
+				}
+				l2Change = s.OrderBook.SaveOrder(logOffset.Offset, log.Sequence, log.OrderId, decimal.Zero, log.Price, log.Side) 
 
 			case *matching.OpenLog: 
 				log := logOffset.Log.(*matching.OpenLog) 
+				if log.RemainingSize.GreaterThan(decimal.Zero){
+					fullMessage = s.newFullMessage("open", log.Base, log.OrderId, log.Side.String()) 
+					fullMessage.Price = log.Price.String() 
+					fullMessage.RemainingSize = log.RemainingSize.String() 
+				}
 				l2Change = s.OrderBook.SaveOrder(logOffset.Offset, log.Sequence, log.OrderId, log.RemainingSize, log.Price, log.Side) 
 
 			case *matching.MatchLog: 
 				log := logOffset.Log.(*matching.MatchLog) 
+				fullMessage = s.newFullMessage("match", log.Base, 0, log.Side.String()) 
+				fullMessage.TradeId = log.TradeId 
+				fullMessage.MakerOrderId = utils.I64ToA(log.MakerOrderId) 
+				fullMessage.TakerOrderId = utils.I64ToA(log.TakerOrderId) 
+				fullMessage.Price = log.Price.String() 
+				fullMessage.Size = log.Size.String() 
+
 				order, found := s.OrderBook.Orders[log.MakerOrderId] 
 				if !found{
-					panic(fmt.Sprintf("should not happen : %+v", log))
+					// hidden orders are not on the book 
+					s.OrderBook.LogOffset = logOffset.Offset 
+					s.OrderBook.LogSeq = log.Sequence 
+					break 
 				}
 				newSize := order.Size.Sub(log.Size) 
 				l2Change = s.OrderBook.SaveOrder(logOffset.Offset, log.Sequence, log.MakerOrderId, newSize, log.Price, log.Side)
-			}
 
-			if lastLevel2Snapshot == nil || s.OrderBook.Seq-lastLevel2Snapshot.Seq > 10{
-				lastLevel2Snapshot = s.OrderBook.SnapshotLevel2(1000) 
-				lastLevel2Snapshots.Store(s.ProductId, lastLevel2Snapshot)
+			case *matching.ChangeLog: 
+				log := logOffset.Log.(*matching.ChangeLog) 
+				repriced := log.NewPrice.GreaterThan(decimal.Zero) && !log.NewPrice.Equal(log.Price) 
+				if order, found := s.OrderBook.Orders[log.OrderId]; found{
+					fullMessage = s.newFullMessage("change", log.Base, log.OrderId, log.Side.String()) 
+					fullMessage.Price = log.Price.String() 
+					fullMessage.OldSize = order.Size.String() 
+					fullMessage.NewSize = log.NewSize.String() 
+					if repriced{
+						fullMessage.NewPrice = log.NewPrice.String() 
+					}
+				}
+
+				if repriced{
+					// a repriced pegged order leaves the level of its old
+					// price for the one of the new price
+					removed := s.OrderBook.SaveOrder(logOffset.Offset, log.Sequence, log.OrderId, decimal.Zero, log.Price, log.Side) 
+					if removed != nil{
+						s.Sub.Publish(string(CHANNEL_LEVEL_2.FormatWithProductId(s.ProductId)), removed)
+					}
+					l2Change = s.OrderBook.SaveOrder(logOffset.Offset, log.Sequence, log.OrderId, log.NewSize, log.NewPrice, log.Side)
+				} else{
+					l2Change = s.OrderBook.SaveOrder(logOffset.Offset, log.Sequence, log.OrderId, log.NewSize, log.Price, log.Side)
+				}
+
+			case *matching.AmendLog: 
+				log := logOffset.Log.(*matching.AmendLog) 
+				if order, found := s.OrderBook.Orders[log.OrderId]; found{
+					fullMessage = s.newFullMessage("change", log.Base, log.OrderId, log.Side.String()) 
+					fullMessage.Price = log.OldPrice.String() 
+					fullMessage.OldSize = order.Size.String() 
+					fullMessage.NewSize = log.RemainingSize.String() 
+					if log.Requeued{
+						// taken off the book, the open which follows puts
+						// it back at the new price
+						fullMessage.NewSize = decimal.Zero.String() 
+						fullMessage.NewPrice = log.Price.String() 
+					}
+				}
+
+				if log.Requeued{
+					// the order shows up again with the open log that follows
+					l2Change = s.OrderBook.SaveOrder(logOffset.Offset, log.Sequence, log.OrderId, decimal.Zero, log.OldPrice, log.Side)
+				} else{
+					l2Change = s.OrderBook.SaveOrder(logOffset.Offset, log.Sequence, log.OrderId, log.RemainingSize, log.Price, log.Side)
+				}
+
+			case *matching.StatusLog: 
+				// the book doesn't change, only keep track of the position
+				log := logOffset.Log.(*matching.StatusLog) 
+				s.OrderBook.LogOffset = logOffset.Offset 
+				s.OrderBook.LogSeq = log.Sequence 
+
+			case *matching.RejectedLog: 
+				// a rejected order never reaches the book and a rejected
+				// amend leaves it as it was, only keep track of the position
+				log := logOffset.Log.(*matching.RejectedLog) 
+				s.OrderBook.LogOffset = logOffset.Offset 
+				s.OrderBook.LogSeq = log.Sequence 
+
+			case *matching.ActivateLog: 
+				// an activated stop order shows up on the book with the open
+				// log that follows, only keep track of the position
+				log := logOffset.Log.(*matching.ActivateLog) 
+				s.OrderBook.LogOffset = logOffset.Offset 
+				s.OrderBook.LogSeq = log.Sequence
+
+				if !log.Hidden{
+					fullMessage = s.newFullMessage("activate", log.Base, log.OrderId, log.Side.String()) 
+					fullMessage.OrderType = log.OrderType.String() 
+					fullMessage.StopPrice = log.StopPrice.String() 
+					if log.Price.GreaterThan(decimal.Zero){
+						fullMessage.Price = log.Price.String() 
+					}
+					if log.Size.GreaterThan(decimal.Zero){
+						fullMessage.Size = log.Size.String() 
+					}
+					if log.Funds.GreaterThan(decimal.Zero){
+						fullMessage.Funds = log.Funds.String() 
+					}
+				}
 			}
 
 			if lastLevel2Snapshot == nil || s.OrderBook.Seq-lastLevel2Snapshot.Seq > 10{
 				lastLevel2Snapshot = s.OrderBook.SnapshotLevel2(1000) 
 				lastLevel2Snapshots.Store(s.ProductId, lastLevel2Snapshot)
+				s.storeSnapshot(lastLevel2Snapshot)
 			}
 
 			if lastFullSnapshot == nil || s.OrderBook.Seq-lastFullSnapshot.Seq > 10000{
 				lastFullSnapshot = s.OrderBook.SnapshotFull() 
-				s.SnapshotCh <- lastFullSnapshot
+				s.storeSnapshot(lastFullSnapshot)
 			}
 
 			if l2Change != nil{
 				s.Sub.Publish(string(CHANNEL_LEVEL_2.FormatWithProductId(s.ProductId)), l2Change)
 			}
 
+			if fullMessage != nil{
+				s.Sub.Publish(CHANNEL_FULL.FormatWithProductId(s.ProductId), fullMessage)
+			}
+
 		case <- time.After(200 *time.Millisecond): 
 			if lastLevel2Snapshot == nil || s.OrderBook.Seq > lastLevel2Snapshot.Seq{
 				lastLevel2Snapshot = s.OrderBook.SnapshotLevel2(1000) 
 				lastLevel2Snapshots.Store(s.ProductId, lastLevel2Snapshot)
+				s.storeSnapshot(lastLevel2Snapshot)
+			}
+
+			// the level3 book of the rest api is read from the full
+			// snapshot, bring it up to date once the book is quiet
+			if lastFullSnapshot == nil || s.OrderBook.LogSeq > lastFullSnapshot.LogSeq{
+				lastFullSnapshot = s.OrderBook.SnapshotFull() 
+				s.storeSnapshot(lastFullSnapshot)
 			}
 		}
 	}
 }
 
+func (s *OrderBookStream) newFullMessage(messageType string, base matching.Base, orderId int64, side string) *FullMessage{
+	msg := &FullMessage{
+		Type: messageType, 
+		Sequence: base.Sequence, 
+		Time: base.Time.Format(time.RFC3339Nano), 
+		ProductId: s.ProductId, 
+		Side: side, 
+	}
+	if orderId != 0{
+		msg.OrderId = utils.I64ToA(orderId) 
+	}
+	return msg 
+}
+
 func (s *OrderBookStream) runSnapshots(){
 	for{
 		select{
+		case <- s.stopCh: 
+			return 
+
 		case snapshot := <- s.SnapshotCh: 
 			switch snapshot.(type){
 			case *OrderBookLevel2Snapshot: 
-				err := sharedSnapshotStore().StoreLevel2(s.ProductId, snapshot.(*OrderBookLevel2Snapshot)) 
+				err := SharedSnapshotStore().StoreLevel2(s.ProductId, snapshot.(*OrderBookLevel2Snapshot)) 
 				if err != nil{
 					logger.Error(err)
 				}
 			case *OrderBookFullSnapshot: 
-				err := sharedSnapshotStore().StoreFull(s.ProductId, snapshot.(*OrderBookFullSnapshot)) 
+				err := SharedSnapshotStore().StoreFull(s.ProductId, snapshot.(*OrderBookFullSnapshot)) 
 				if err != nil{
 					logger.Error(err)
 				}
@@ -152,6 +354,16 @@ func (s *OrderBookStream) runSnapshots(){
 	}
 }
 
+// storeSnapshot hands a snapshot over to be saved to redis, it is dropped when
+// the store lags behind, a newer one follows anyway
+func (s *OrderBookStream) storeSnapshot(snapshot interface{}){
+	select{
+	case s.SnapshotCh <- snapshot: 
+	default: 
+		logger.Warnf("snapshot store of %v lags behind, drop snapshot", s.ProductId)
+	}
+}
+
 func getLastLevel2Snnapshot(productId string) *OrderBookLevel2Snapshot{
 	snapshot, found := lastLevel2Snapshots.Load(productId) 
 	if !found{
@@ -75,6 +75,7 @@ func (r *RedisStream) Start(){
 						ExecutedValue: order.ExecutedValue.String(), 
 						Status: string(order.Status), 
 						Settled: order.Settled,
+						RejectReason: string(order.RejectReason),
 					})
 				}
 			}
@@ -1,6 +1,7 @@
 package publisher
 
 import (
+	"fmt"
 	"strconv"
 	"sync"
 	"time"
@@ -19,8 +20,6 @@ var lastTickers sync.Map
 type TickerStream struct {
 	ProductId      string
 	Sub            *Subscription
-	BestBid        decimal.Decimal
-	BestAsk        decimal.Decimal
 	LogReader      matching.LogReader
 	LastTickerTime int64
 }
@@ -41,6 +40,14 @@ func (s *TickerStream) Start() {
 	go s.LogReader.Run(0, -1)
 }
 
+func (s *TickerStream) Stop() {
+	s.LogReader.Stop()
+}
+
+func (s *TickerStream) OnReceivedLog(log *matching.ReceivedLog, offset int64) {
+	// do nothing
+}
+
 func (s *TickerStream) OnOpenLOg(log *matching.OpenLog, offset int64) {
 	// do nothing
 }
@@ -49,6 +56,26 @@ func (s *TickerStream) OnDoneLog(log *matching.DoneLog, offset int64) {
 	// do nothing
 }
 
+func (s *TickerStream) OnActivateLog(log *matching.ActivateLog, offset int64) {
+	// do nothing
+}
+
+func (s *TickerStream) OnChangeLog(log *matching.ChangeLog, offset int64) {
+	// do nothing
+}
+
+func (s *TickerStream) OnAmendLog(log *matching.AmendLog, offset int64) {
+	// do nothing
+}
+
+func (s *TickerStream) OnStatusLog(log *matching.StatusLog, offset int64) {
+	// do nothing
+}
+
+func (s *TickerStream) OnRejectedLog(log *matching.RejectedLog, offset int64) {
+	// do nothing
+}
+
 func (s *TickerStream) OnMatchLog(log *matching.MatchLog, offset int64) {
 	if time.Now().Unix()-s.LastTickerTime > intervalSec {
 		ticker, err := s.newTickerMessage(log)
@@ -61,45 +88,106 @@ func (s *TickerStream) OnMatchLog(log *matching.MatchLog, offset int64) {
 			return
 		}
 
-		lastTickers.Store(log.ProductId, ticker)
+		lastTickers.Store(s.ProductId, ticker)
 		s.Sub.Publish(CHANNEL_TICKER.FormatWithProductId(strconv.Itoa(int(log.ProductId))), ticker)
 		s.LastTickerTime = time.Now().Unix()
 	}
 }
 
 func (s *TickerStream) newTickerMessage(log *matching.MatchLog) (*TickerMessage, error) {
-	ticks24h, err := service.GetTicksByProductId(s.ProductId, 1*60, 24)
+	ticker := &TickerMessage{
+		Type: "ticker", 
+		TradeId: log.TradeId, 
+		Sequence: log.Sequence, 
+		Time: log.Time.Format(time.RFC3339), 
+		ProductId: s.ProductId, 
+		Price: log.Price.String(), 
+		Side: log.Side.String(), 
+		LastSize: log.Size.String(), 
+	}
+
+	// the order book stream of the product runs alongside
+	snapshot := getLastLevel2Snnapshot(s.ProductId) 
+	if snapshot != nil{
+		setBestBidAsk(ticker, snapshot)
+	}
+
+	err := setTickerStats(ticker, s.ProductId) 
+	if err != nil{
+		return nil, err 
+	}
+	return ticker, nil 
+}
+
+// NewProductTicker builds the ticker of a product from the last trade, the last
+// level2 snapshot in redis and the ticks, for those who don't follow the stream
+func NewProductTicker(productId string) (*TickerMessage, error){
+	ticker := &TickerMessage{Type: "ticker", ProductId: productId} 
+
+	trades, err := service.GetTradesByProductId(productId, 1) 
+	if err != nil{
+		return nil, err 
+	}
+	if len(trades) > 0{
+		trade := trades[0] 
+		ticker.TradeId = trade.TradeId 
+		ticker.Sequence = trade.LogSeq 
+		ticker.Time = trade.Time.Format(time.RFC3339) 
+		ticker.Price = trade.Price.String() 
+		ticker.Side = trade.Side.String() 
+		ticker.LastSize = trade.Size.String() 
+	}
+
+	snapshot, err := SharedSnapshotStore().GetLastLevel2(productId) 
+	if err != nil{
+		return nil, err 
+	}
+	if snapshot != nil{
+		setBestBidAsk(ticker, snapshot)
+	}
+
+	err = setTickerStats(ticker, productId) 
+	if err != nil{
+		return nil, err 
+	}
+	return ticker, nil 
+}
+
+func setBestBidAsk(ticker *TickerMessage, snapshot *OrderBookLevel2Snapshot){
+	if len(snapshot.Bids) > 0{
+		ticker.BestBid = fmt.Sprint(snapshot.Bids[0][0])
+	}
+	if len(snapshot.Asks) > 0{
+		ticker.BestAsk = fmt.Sprint(snapshot.Asks[0][0])
+	}
+}
+
+// setTickerStats sets the 24h open, high, low and volume and the 30d volume
+func setTickerStats(ticker *TickerMessage, productId string) error{
+	ticks24h, err := service.GetTicksByProductId(productId, 1*60, 24)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	tick24h := mergeTicks(ticks24h)
 	if tick24h == nil {
 		tick24h = &entities.Tick{}
 	}
 
-	ticks30d, err := service.GetTicksByProductId(s.ProductId, 24*60, 30) 
+	ticks30d, err := service.GetTicksByProductId(productId, 24*60, 30) 
 	if err != nil{
-		return nil, err 
+		return err 
 	}
 	tick30d := mergeTicks(ticks30d) 
 	if tick30d == nil{
 		tick30d = &entities.Tick{} 
 	}
 
-	return &TickerMessage{
-		Type: "ticker", 
-		TradeId: log.TradeId, 
-		Sequence: log.Sequence, 
-		Time: log.Time.Format(time.RFC3339), 
-		ProductId: strconv.Itoa(int(log.ProductId)), 
-		Price: log.Price.String(), 
-		Side: log.Side.String(), 
-		LastSize: log.Size.String(), 
-		Open24h: tick24h.Open.String(),
-		Low24h: tick24h.Low.String(), 
-		Volume24h: tick24h.Volume.String(), 
-		Volume30d: tick30d.Volume.String(),
-	}, nil 
+	ticker.Open24h = tick24h.Open.String() 
+	ticker.High24h = tick24h.High.String() 
+	ticker.Low24h = tick24h.Low.String() 
+	ticker.Volume24h = tick24h.Volume.String() 
+	ticker.Volume30d = tick30d.Volume.String() 
+	return nil 
 }
 
 func mergeTicks(ticks []*entities.Tick) *entities.Tick{
@@ -0,0 +1,216 @@
+package restapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/irononet/go-exchange/entities"
+	"github.com/irononet/go-exchange/matching"
+	"github.com/irononet/go-exchange/service"
+	"github.com/siddontang/go-log/log"
+)
+
+const (
+	recordSentRetries       = 3
+	recordSentRetryInterval = 500 * time.Millisecond
+)
+
+// POST /admin/products
+func AddProduct(ctx *gin.Context) {
+	var req productRequest
+	err := ctx.BindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, newMessageVo(err))
+		return
+	}
+
+	product := &entities.Product{TradingStatus: entities.TradingStatus(req.TradingStatus)}
+	req.applyTo(product)
+
+	err = service.AddProduct(product)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, newMessageVo(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newProductVo(product))
+}
+
+// PUT /admin/products/<product-id>
+func UpdateProduct(ctx *gin.Context) {
+	var req productRequest
+	err := ctx.BindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, newMessageVo(err))
+		return
+	}
+
+	product, err := service.GetProductById(ctx.Param("productId"))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, newMessageVo(err))
+		return
+	}
+	if product == nil {
+		ctx.JSON(http.StatusNotFound, newMessageVo(errors.New("product not found")))
+		return
+	}
+
+	if product.BaseCurrency != req.BaseCurrency || product.QuoteCurrency != req.QuoteCurrency {
+		ctx.JSON(http.StatusBadRequest, newMessageVo(errors.New("the currencies of a product can't change")))
+		return
+	}
+	req.applyTo(product)
+
+	err = service.CheckProduct(product)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, newMessageVo(err))
+		return
+	}
+
+	// the engine of the product applies the new limits and matching
+	// algorithm in order with the other commands, it's sent them before
+	// they're recorded so the product never shows settings the engine
+	// doesn't have
+	err = submitCommand(ctx.Param("productId"), matching.NewProductSettingsCommand(product))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, newMessageVo(err))
+		return
+	}
+
+	err = recordSent(func() error { return service.UpdateProduct(product) })
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, newMessageVo(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newProductVo(product))
+}
+
+// POST /admin/products/<product-id>/pause
+func PauseProduct(ctx *gin.Context) {
+	setProductStatus(ctx, entities.ProductStatusPaused)
+}
+
+// POST /admin/products/<product-id>/resume
+func ResumeProduct(ctx *gin.Context) {
+	setProductStatus(ctx, entities.ProductStatusOnline)
+}
+
+// POST /admin/products/<product-id>/delist
+//
+// The product is paused and its orders are cancelled first. While some are
+// left 409 is returned, and the delist is to be sent again once they are done.
+func DelistProduct(ctx *gin.Context) {
+	productId := ctx.Param("productId")
+
+	orders, err := service.GetActiveOrdersByProductId(productId, 1000)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, newMessageVo(err))
+		return
+	}
+
+	if len(orders) == 0 {
+		setProductStatus(ctx, entities.ProductStatusDelisted)
+		return
+	}
+
+	_, err = service.SetProductStatus(productId, entities.ProductStatusPaused)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, newMessageVo(err))
+		return
+	}
+
+	for _, order := range orders {
+		err = submitCommand(strconv.Itoa(order.ProductId), matching.NewCancelCommand(order))
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, newMessageVo(err))
+			return
+		}
+	}
+
+	ctx.JSON(http.StatusConflict, newMessageVo(fmt.Errorf("cancelling %v orders, delist again once they are done", len(orders))))
+}
+
+// PUT /admin/products/<product-id>/tradingStatus
+func SetTradingStatus(ctx *gin.Context) {
+	var req tradingStatusRequest
+	err := ctx.BindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, newMessageVo(err))
+		return
+	}
+
+	product, err := service.CheckTradingStatus(ctx.Param("productId"), entities.TradingStatus(req.Status), req.AuctionEnd)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, newMessageVo(err))
+		return
+	}
+
+	// the engine of the product is sent the status before it's recorded, so
+	// the product never shows a status the engine doesn't have. The status
+	// log of the engine is recorded as well, which catches up with a record
+	// failed here
+	err = submitCommand(ctx.Param("productId"), matching.NewProductControlCommand(req.Status, req.AuctionEnd))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, newMessageVo(err))
+		return
+	}
+
+	err = recordSent(func() error { return service.RecordTradingStatus(int64(product.ID), product.TradingStatus) })
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, newMessageVo(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newProductVo(product))
+}
+
+// recordSent records what the engine of a product has been sent, retrying a
+// few times since the engine already has it
+func recordSent(record func() error) error {
+	var err error
+	for i := 0; i < recordSentRetries; i++ {
+		if i > 0 {
+			time.Sleep(recordSentRetryInterval)
+		}
+		err = record()
+		if err == nil {
+			return nil
+		}
+		log.Error(err)
+	}
+	return err
+}
+
+func setProductStatus(ctx *gin.Context, status entities.ProductStatus) {
+	product, err := service.SetProductStatus(ctx.Param("productId"), status)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, newMessageVo(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newProductVo(product))
+}
+
+func (r *productRequest) applyTo(product *entities.Product) {
+	product.BaseCurrency = r.BaseCurrency
+	product.QuoteCurrency = r.QuoteCurrency
+	product.BaseMinSize = r.BaseMinSize
+	product.BaseMaxSize = r.BaseMaxSize
+	product.QuoteMinSize = r.QuoteMinSize
+	product.QuoteMaxSize = r.QuoteMaxSize
+	product.QuoteIncrement = r.QuoteIncrement
+	product.BaseScale = r.BaseScale
+	product.QuoteScale = r.QuoteScale
+	product.PriceLimits = entities.PriceLimits{
+		PriceBandPercent:            r.PriceBandPercent,
+		CircuitBreakerPercent:       r.CircuitBreakerPercent,
+		CircuitBreakerWindowSeconds: r.CircuitBreakerWindowSeconds,
+		CircuitBreakerHaltSeconds:   r.CircuitBreakerHaltSeconds,
+	}
+	product.MatchingAlgorithm = entities.MatchingAlgorithm(r.MatchingAlgorithm)
+}
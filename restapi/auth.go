@@ -3,6 +3,7 @@ package restapi
 import (
 	"errors" 
 	"github.com/gin-gonic/gin" 
+	"github.com/irononet/go-exchange/conf" 
 	"github.com/irononet/go-exchange/entities" 
 	"github.com/irononet/go-exchange/service" 
 	"net/http"
@@ -38,6 +39,24 @@ func CheckToken() gin.HandlerFunc{
 	}
 }
 
+// CheckAdmin only lets the admins configured for the rest server through, it
+// comes after CheckToken
+func CheckAdmin() gin.HandlerFunc{
+	return func(c *gin.Context){
+		user := GetCurrentUser(c) 
+		if user != nil{
+			for _, email := range conf.GetConfig().RestServer.AdminEmails{
+				if email == user.Email{
+					c.Next() 
+					return 
+				}
+			}
+		}
+
+		c.AbortWithStatusJSON(http.StatusForbidden, newMessageVo(errors.New("admin only")))
+	}
+}
+
 func GetCurrentUser(ctx *gin.Context) *entities.User{
 	val, found := ctx.Get(KeyCurrentUser) 
 	if !found{
@@ -0,0 +1,40 @@
+package restapi
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/irononet/go-exchange/service"
+)
+
+// GET /fills?productId=&orderId=&before=&after=&limit=
+func GetFills(ctx *gin.Context) {
+	productId := ctx.Query("productId")
+	orderId, _ := strconv.ParseInt(ctx.Query("orderId"), 10, 64)
+	before, _ := strconv.ParseInt(ctx.Query("before"), 10, 64)
+	after, _ := strconv.ParseInt(ctx.Query("after"), 10, 64)
+	limit, _ := strconv.ParseInt(ctx.Query("limit"), 10, 64)
+
+	fills, err := service.GetFillsByUserId(int64(GetCurrentUser(ctx).ID), productId, orderId, before, after, int(limit))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, newMessageVo(err))
+		return
+	}
+
+	fillVos := []*fillVo{}
+	for _, fill := range fills {
+		fillVos = append(fillVos, newFillVo(fill))
+	}
+
+	var newBefore, newAfter int64 = 0, 0
+	if len(fills) > 0 {
+		newBefore = int64(fills[0].ID)
+		newAfter = int64(fills[len(fills)-1].ID)
+	}
+
+	ctx.Header("gex-before", strconv.FormatInt(newBefore, 10))
+	ctx.Header("gex-after", strconv.FormatInt(newAfter, 10))
+
+	ctx.JSON(http.StatusOK, fillVos)
+}
@@ -1,72 +1,74 @@
 package restapi
 
 import (
-	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"github.com/irononet/go-exchange/conf"
 	"github.com/irononet/go-exchange/entities"
 	"github.com/irononet/go-exchange/matching"
 	"github.com/irononet/go-exchange/service"
 	"github.com/irononet/go-exchange/utils"
-	"github.com/segmentio/kafka-go"
 	"github.com/shopspring/decimal"
 	"github.com/siddontang/go-log/log"
 )
 
-var productId2Writer sync.Map
-
-func getWriter(productId string) *kafka.Writer {
-	writer, found := productId2Writer.Load(productId)
-	if found {
-		return writer.(*kafka.Writer)
+// submitCommand writes a command to the order topic of the product. Nothing is
+// applied by the engine if it fails.
+func submitCommand(productId string, command *matching.Command) error {
+	err := matching.SharedCommandWriter(productId).Write(command)
+	if err != nil {
+		log.Error(err)
 	}
-
-	gexConfig := conf.GetConfig()
-
-	newWriter := kafka.NewWriter(kafka.WriterConfig{
-		Brokers:      gexConfig.Kafka.Brokers,
-		Topic:        matching.TopicOrderPrefix + productId,
-		Balancer:     &kafka.LeastBytes{},
-		BatchTimeout: 5 * time.Millisecond,
-	})
-	productId2Writer.Store(productId, newWriter)
-	return newWriter
+	return err
 }
 
-func submitOrder(order *entities.Order) {
-	buf, err := json.Marshal(order)
+// POST /orders
+func PlaceOrder(ctx *gin.Context) {
+	var req placeOrderRequest
+	err := ctx.BindJSON(&req)
 	if err != nil {
-		log.Error(err)
+		ctx.JSON(http.StatusBadRequest, newMessageVo(err))
 		return
 	}
 
-	productIdStr := strconv.Itoa(order.ProductId)
-	err = getWriter(productIdStr).WriteMessages(context.Background(), kafka.Message{Value: buf})
+	leg, err := newOrderGroupLeg(&req)
 	if err != nil {
-		log.Error(err)
+		ctx.JSON(http.StatusBadRequest, newMessageVo(err))
+		return
 	}
 
-}
+	order, err := service.PlaceOrder(int64(GetCurrentUser(ctx).ID), leg.ClientUid, req.ProductId, leg.Type, leg.Side,
+		leg.Size, leg.Price, leg.Funds, leg.Opts)
+	if err != nil {
+		var ruleErr *service.ProductRuleError
+		if errors.As(err, &ruleErr) {
+			ctx.JSON(http.StatusBadRequest, newProductRuleErrorVo(ruleErr))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, newMessageVo(err))
+		return
+	}
 
-// POST /orders
-func PlaceOrder(ctx *gin.Context) {
-	var req placeOrderRequest
-	err := ctx.BindJSON(&req)
+	err = submitCommand(strconv.Itoa(order.ProductId), matching.NewOrderCommand(order))
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, newMessageVo(err))
+		if rejectErr := service.RejectUnsentOrders([]*entities.Order{order}); rejectErr != nil {
+			log.Error(rejectErr)
+		}
+		ctx.JSON(http.StatusInternalServerError, newMessageVo(err))
 		return
 	}
 
+	ctx.JSON(http.StatusOK, newOrderVo(order))
+}
+
+// newOrderGroupLeg reads the order of a placement request
+func newOrderGroupLeg(req *placeOrderRequest) (*service.OrderGroupLeg, error) {
 	side := entities.Side(req.Side)
 	if len(side) == 0 {
 		side = entities.SideBuy
@@ -78,26 +80,99 @@ func PlaceOrder(ctx *gin.Context) {
 	}
 
 	if len(req.ClientOid) > 0 {
-		_, err = uuid.Parse(req.ClientOid)
+		_, err := uuid.Parse(req.ClientOid)
 		if err != nil {
-			ctx.JSON(http.StatusBadRequest, newMessageVo(fmt.Errorf("invalid client_oid: %v", err)))
-			return
+			return nil, fmt.Errorf("invalid client_oid: %v", err)
 		}
 	}
 
-	size := decimal.NewFromFloat(req.Size)
-	price := decimal.NewFromFloat(req.Price)
-	funds := decimal.NewFromFloat(req.Funds)
+	opts := service.OrderOptions{
+		StopPrice:   decimal.NewFromFloat(req.StopPrice),
+		TimeInForce: entities.TimeInForce(req.TimeInForce),
+		PostOnly:    req.PostOnly,
+		DisplaySize: decimal.NewFromFloat(req.DisplaySize),
+		Hidden:      req.Hidden,
+		PegType:     entities.PegType(req.PegType),
+		PegOffset:   decimal.NewFromFloat(req.PegOffset),
+
+		TrailAmount:  decimal.NewFromFloat(req.TrailAmount),
+		TrailPercent: decimal.NewFromFloat(req.TrailPercent),
+
+		SelfTradePrevention: entities.SelfTradePrevention(req.Stp),
+	}
+
+	if len(req.ExpireTime) > 0 {
+		var err error
+		opts.ExpireTime, err = time.Parse(time.RFC3339, req.ExpireTime)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expireTime: %v", err)
+		}
+	}
+
+	return &service.OrderGroupLeg{
+		ClientUid: req.ClientOid,
+		Type:      orderType,
+		Side:      side,
+		Size:      decimal.NewFromFloat(req.Size),
+		Price:     decimal.NewFromFloat(req.Price),
+		Funds:     decimal.NewFromFloat(req.Funds),
+		Opts:      opts,
+	}, nil
+}
+
+// PUT /orders/1
+// PUT /orders/client:1
+func AmendOrder(ctx *gin.Context) {
+	var req amendOrderRequest
+	err := ctx.BindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, newMessageVo(err))
+		return
+	}
+
+	rawOrderId := ctx.Param("orderId")
+
+	var order *entities.Order
+	if strings.HasPrefix(rawOrderId, "client:") {
+		clientOid := strings.Split(rawOrderId, ":")[1]
+		order, err = service.GetOrderByClientUid(int64(GetCurrentUser(ctx).ID), clientOid)
+	} else {
+		orderId, _ := utils.AToInt64(rawOrderId)
+		order, err = service.GetOrderById(orderId)
+	}
 
-	order, err := service.PlaceOrder(int64(GetCurrentUser(ctx).ID), req.ClientOid, req.ProductId, orderType, side, size, price, funds)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, newMessageVo(err))
 		return
 	}
 
-	submitOrder(order)
+	if order == nil || order.UserId != int(GetCurrentUser(ctx).ID) {
+		ctx.JSON(http.StatusNotFound, newMessageVo(errors.New("order not found")))
+		return
+	}
 
-	ctx.JSON(http.StatusOK, order)
+	amend, err := service.AmendOrder(int64(order.ID), decimal.NewFromFloat(req.Size), decimal.NewFromFloat(req.Price))
+	if err != nil {
+		var ruleErr *service.ProductRuleError
+		if errors.As(err, &ruleErr) {
+			ctx.JSON(http.StatusBadRequest, newProductRuleErrorVo(ruleErr))
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, newMessageVo(err))
+		return
+	}
+
+	err = submitCommand(strconv.Itoa(amend.ProductId), matching.NewAmendCommand(amend))
+	if err != nil {
+		// the engine never sees the amend, release what was held for it
+		if cancelErr := service.CancelAmend(int64(amend.ID)); cancelErr != nil {
+			log.Error(cancelErr)
+		}
+		ctx.JSON(http.StatusInternalServerError, newMessageVo(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newOrderVo(amend))
 }
 
 // DELETE /orders/1
@@ -125,13 +200,18 @@ func CancelOrder(ctx *gin.Context) {
 		return
 	}
 
-	order.Status = entities.OrderStatusCancelling
-	submitOrder(order)
+	err = submitCommand(strconv.Itoa(order.ProductId), matching.NewCancelCommand(order))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, newMessageVo(err))
+		return
+	}
 
 	ctx.JSON(http.StatusOK, nil)
 }
 
 // DELETE /orders/?productId=BTC-USD&side=[buy, sell]
+// The orders are cancelled by the matching engine in a single step, on every
+// product if productId is empty
 func CancelOrders(ctx *gin.Context) {
 	productId := ctx.Query("productId")
 
@@ -147,15 +227,26 @@ func CancelOrders(ctx *gin.Context) {
 
 	}
 
-	orders, err := service.GetOrdersByUserId(int64(GetCurrentUser(ctx).ID), []entities.OrderStatus{entities.OrderStatusOpen, entities.OrderStatusNew}, side, productId, 0, 0, 10000)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, newMessageVo(err))
-		return
+	productIds := []string{productId}
+	if len(productId) == 0 {
+		products, err := service.GetProducts()
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, newMessageVo(err))
+			return
+		}
+
+		productIds = nil
+		for _, product := range products {
+			productIds = append(productIds, strconv.Itoa(int(product.ID)))
+		}
 	}
 
-	for _, order := range orders {
-		order.Status = entities.OrderStatusCancelling
-		submitOrder(order)
+	for _, productId := range productIds {
+		err := submitCommand(productId, matching.NewMassCancelCommand(int64(GetCurrentUser(ctx).ID), side))
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, newMessageVo(err))
+			return
+		}
 	}
 
 	ctx.JSON(http.StatusOK, nil)
@@ -0,0 +1,85 @@
+package restapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/irononet/go-exchange/entities"
+	"github.com/irononet/go-exchange/matching"
+	"github.com/irononet/go-exchange/service"
+	"github.com/irononet/go-exchange/utils"
+	"github.com/shopspring/decimal"
+	"github.com/siddontang/go-log/log"
+)
+
+// POST /orders/groups
+func PlaceOrderGroup(ctx *gin.Context) {
+	var req placeOrderGroupRequest
+	err := ctx.BindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, newMessageVo(err))
+		return
+	}
+
+	groupType, err := entities.NewOrderGroupTypeFromString(req.Type)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, newMessageVo(err))
+		return
+	}
+
+	userId := int64(GetCurrentUser(ctx).ID)
+
+	var group *entities.OrderGroup
+	var orders []*entities.Order
+	switch *groupType {
+	case entities.OrderGroupTypeOco:
+		var legs []service.OrderGroupLeg
+		for _, legReq := range req.Orders {
+			leg, err := newOrderGroupLeg(legReq)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, newMessageVo(err))
+				return
+			}
+			legs = append(legs, *leg)
+		}
+		group, orders, err = service.PlaceOcoGroup(userId, req.ProductId, legs)
+	case entities.OrderGroupTypeBracket:
+		if req.Entry == nil {
+			ctx.JSON(http.StatusBadRequest, newMessageVo(errors.New("bracket without entry")))
+			return
+		}
+		var entry *service.OrderGroupLeg
+		entry, err = newOrderGroupLeg(req.Entry)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, newMessageVo(err))
+			return
+		}
+		group, orders, err = service.PlaceBracketGroup(userId, req.ProductId, *entry,
+			decimal.NewFromFloat(req.TakeProfitPrice), decimal.NewFromFloat(req.StopLossPrice),
+			decimal.NewFromFloat(req.StopLossLimitPrice))
+	default:
+		err = fmt.Errorf("unsupported order group type: %v", *groupType)
+	}
+	if err != nil {
+		var ruleErr *service.ProductRuleError
+		if errors.As(err, &ruleErr) {
+			ctx.JSON(http.StatusBadRequest, newProductRuleErrorVo(ruleErr))
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, newMessageVo(err))
+		return
+	}
+
+	err = submitCommand(utils.I64ToA(group.ProductId), matching.NewOrderGroupCommand(group, orders))
+	if err != nil {
+		if rejectErr := service.RejectUnsentOrders(orders); rejectErr != nil {
+			log.Error(rejectErr)
+		}
+		ctx.JSON(http.StatusInternalServerError, newMessageVo(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newOrderGroupVo(group, orders))
+}
@@ -1,12 +1,18 @@
 package restapi 
 
 import (
+	"errors" 
+	"fmt" 
 	"github.com/gin-gonic/gin" 
+	"github.com/irononet/go-exchange/publisher" 
 	"github.com/irononet/go-exchange/service" 
 	"github.com/irononet/go-exchange/utils" 
 	"net/http" 
 )
 
+// price levels of a level 2 order book
+const orderBookLevel2Depth = 50
+
 // GET /products 
 func GetProducts(ctx *gin.Context){
 	products, err := service.GetProducts() 
@@ -25,12 +31,97 @@ func GetProducts(ctx *gin.Context){
 
 // Get products/<product-id>/book?level=[1, 2, 3] 
 func GetProductOrderBook(ctx *gin.Context){
+	productId := ctx.Param("productId") 
+	level := ctx.DefaultQuery("level", "1") 
+
+	product, err := service.GetProductById(productId) 
+	if err != nil{
+		ctx.JSON(http.StatusInternalServerError, newMessageVo(err)) 
+		return 
+	}
+	if product == nil{
+		ctx.JSON(http.StatusNotFound, newMessageVo(errors.New("product not found"))) 
+		return 
+	}
+
+	switch level{
+	case "1", "2": 
+		// [price, size, order count]
+		snapshot, err := publisher.SharedSnapshotStore().GetLastLevel2(productId) 
+		if err != nil{
+			ctx.JSON(http.StatusInternalServerError, newMessageVo(err)) 
+			return 
+		}
+		if snapshot == nil{
+			ctx.JSON(http.StatusOK, &orderBookVo{Sequence: "0", Asks: [][3]interface{}{}, Bids: [][3]interface{}{}}) 
+			return 
+		}
+
+		levels := 1 
+		if level == "2"{
+			levels = orderBookLevel2Depth 
+		}
+		ctx.JSON(http.StatusOK, &orderBookVo{
+			Sequence: utils.I64ToA(snapshot.Seq), 
+			Asks: snapshot.Asks[:utils.MinInt(levels, len(snapshot.Asks))], 
+			Bids: snapshot.Bids[:utils.MinInt(levels, len(snapshot.Bids))],
+		})
+
+	case "3": 
+		// [price, size, order id]
+		snapshot, err := publisher.SharedSnapshotStore().GetLastFull(productId) 
+		if err != nil{
+			ctx.JSON(http.StatusInternalServerError, newMessageVo(err)) 
+			return 
+		}
+		if snapshot == nil{
+			ctx.JSON(http.StatusOK, &orderBookVo{Sequence: "0", Asks: [][3]interface{}{}, Bids: [][3]interface{}{}}) 
+			return 
+		}
+		ctx.JSON(http.StatusOK, newLevel3OrderBookVo(snapshot))
 
+	default: 
+		ctx.JSON(http.StatusBadRequest, newMessageVo(fmt.Errorf("invalid level: %v", level)))
+	}
 }
 
 // GET /products/<product-id>/ticker 
-func GetProductTicker(){
-	// Todo 
+func GetProductTicker(ctx *gin.Context){
+	ticker, ok := getProductTicker(ctx) 
+	if !ok{
+		return 
+	}
+	ctx.JSON(http.StatusOK, newTickerVo(ticker))
+}
+
+// GET /products/<product-id>/stats 
+func GetProductStats(ctx *gin.Context){
+	ticker, ok := getProductTicker(ctx) 
+	if !ok{
+		return 
+	}
+	ctx.JSON(http.StatusOK, newStatsVo(ticker))
+}
+
+func getProductTicker(ctx *gin.Context) (*publisher.TickerMessage, bool){
+	productId := ctx.Param("productId") 
+
+	product, err := service.GetProductById(productId) 
+	if err != nil{
+		ctx.JSON(http.StatusInternalServerError, newMessageVo(err)) 
+		return nil, false 
+	}
+	if product == nil{
+		ctx.JSON(http.StatusNotFound, newMessageVo(errors.New("product not found"))) 
+		return nil, false 
+	}
+
+	ticker, err := publisher.NewProductTicker(productId) 
+	if err != nil{
+		ctx.JSON(http.StatusInternalServerError, newMessageVo(err)) 
+		return nil, false 
+	}
+	return ticker, true 
 }
 
 // GET /products/<product-id>/trades 
@@ -0,0 +1,59 @@
+package restapi
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/irononet/go-exchange/entities"
+	"github.com/irononet/go-exchange/matching"
+	"github.com/irononet/go-exchange/service"
+	"github.com/shopspring/decimal"
+	"github.com/siddontang/go-log/log"
+)
+
+// POST /quotes
+func PlaceMassQuote(ctx *gin.Context) {
+	var req massQuoteRequest
+	err := ctx.BindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, newMessageVo(err))
+		return
+	}
+
+	var levels []service.QuoteLevel
+	for _, quote := range req.Quotes {
+		levels = append(levels, service.QuoteLevel{
+			Side:  entities.Side(quote.Side),
+			Price: decimal.NewFromFloat(quote.Price),
+			Size:  decimal.NewFromFloat(quote.Size),
+		})
+	}
+
+	userId := int64(GetCurrentUser(ctx).ID)
+	orders, err := service.PlaceMassQuote(userId, req.ProductId, levels, req.PostOnly)
+	if err != nil {
+		var ruleErr *service.ProductRuleError
+		if errors.As(err, &ruleErr) {
+			ctx.JSON(http.StatusBadRequest, newProductRuleErrorVo(ruleErr))
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, newMessageVo(err))
+		return
+	}
+
+	err = submitCommand(req.ProductId, matching.NewMassQuoteCommand(userId, orders))
+	if err != nil {
+		if rejectErr := service.RejectUnsentOrders(orders); rejectErr != nil {
+			log.Error(rejectErr)
+		}
+		ctx.JSON(http.StatusInternalServerError, newMessageVo(err))
+		return
+	}
+
+	orderVos := []*orderVo{}
+	for _, order := range orders {
+		orderVos = append(orderVos, newOrderVo(order))
+	}
+	ctx.JSON(http.StatusOK, &massQuoteVo{ProductId: req.ProductId, Orders: orderVos})
+}
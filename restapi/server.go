@@ -31,14 +31,20 @@ func (server *HttpServer) Start(){
 	r.GET("/api/products", GetProducts) 
 	r.GET("/api/products/:productId/trades", GetProductTrades) 
 	r.GET("/api/products/:productId/book", GetProductOrderBook) 
+	r.GET("/api/products/:productId/ticker", GetProductTicker) 
+	r.GET("/api/products/:productId/stats", GetProductStats) 
 	r.GET("/api/products/:productId/candles", GetProductCandles) 
 
 	private := r.Group("/", CheckToken())
 	{
 		private.GET("/api/orders", GetOrders) 
 		private.POST("/api/orders", PlaceOrder) 
+		private.POST("/api/orders/groups", PlaceOrderGroup) 
+		private.POST("/api/quotes", PlaceMassQuote) 
+		private.PUT("/api/orders/:orderId", AmendOrder) 
 		private.DELETE("/api/orders/:orderId", CancelOrder) 
 		private.DELETE("/api/orders", CancelOrders) 
+		private.GET("/api/fills", GetFills) 
 		private.GET("/api/accounts", GetAccounts) 
 		private.GET("/api/users/self", GetUserSelf) 
 		private.POST("/api/users/password", ChangePassword) 
@@ -48,6 +54,16 @@ func (server *HttpServer) Start(){
 		private.POST("/api/wallets/:currency/withdrawal", Withdrawal)
 	}
 
+	admin := r.Group("/", CheckToken(), CheckAdmin())
+	{
+		admin.POST("/api/admin/products", AddProduct) 
+		admin.PUT("/api/admin/products/:productId", UpdateProduct) 
+		admin.POST("/api/admin/products/:productId/pause", PauseProduct) 
+		admin.POST("/api/admin/products/:productId/resume", ResumeProduct) 
+		admin.POST("/api/admin/products/:productId/delist", DelistProduct)
+		admin.PUT("/api/admin/products/:productId/tradingStatus", SetTradingStatus)
+	}
+
 	err := r.Run(server.Addr) 
 	if err != nil{
 		panic(err)
@@ -2,12 +2,17 @@ package restapi
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/irononet/go-exchange/entities"
+	"github.com/irononet/go-exchange/matching"
+	"github.com/irononet/go-exchange/publisher"
+	"github.com/irononet/go-exchange/service"
 	"github.com/irononet/go-exchange/utils"
+	"github.com/shopspring/decimal"
 )
 
 type messageVo struct {
@@ -20,6 +25,26 @@ func newMessageVo(error error) *messageVo {
 	}
 }
 
+// productRuleErrorVo tells the client which field of the order to fix and the
+// limit it has to keep to
+type productRuleErrorVo struct {
+	Message string `json:"message"`
+	Rule    string `json:"rule"`
+	Field   string `json:"field"`
+	Value   string `json:"value"`
+	Limit   string `json:"limit"`
+}
+
+func newProductRuleErrorVo(err *service.ProductRuleError) *productRuleErrorVo {
+	return &productRuleErrorVo{
+		Message: err.Error(),
+		Rule:    string(err.Rule),
+		Field:   err.Field,
+		Value:   err.Value.String(),
+		Limit:   err.Limit.String(),
+	}
+}
+
 type accountVo struct {
 	Id           string `json:"id"`
 	Currency     string `json:"currency"`
@@ -33,28 +58,96 @@ type placeOrderRequest struct {
 	ProductId   string  `json:"productId"`
 	Size        float64 `json:"size"`
 	Price 		float64	`json:"price"`
+	StopPrice   float64 `json:"stopPrice"`
 	Funds       float64 `json:"funds"`
 	Side        string  `json:"side"`
-	Type        string  `json:"side"`
+	Type        string  `json:"type"`
 	TimeInForce string  `json:"timeInForce"`
+	ExpireTime  string  `json:"expireTime"`
+	PostOnly    bool    `json:"postOnly"`
+	Stp         string  `json:"stp"`
+	DisplaySize float64 `json:"displaySize"`
+	Hidden      bool    `json:"hidden"`
+	PegType     string  `json:"pegType"`
+	PegOffset   float64 `json:"pegOffset"`
+	TrailAmount  float64 `json:"trailAmount"`
+	TrailPercent float64 `json:"trailPercent"`
+}
+
+// placeOrderGroupRequest places the legs of an OCO group, or the entry of a
+// bracket with its take-profit and stop-loss prices
+type placeOrderGroupRequest struct {
+	ProductId          string               `json:"productId"`
+	Type               string               `json:"type"`
+	Orders             []*placeOrderRequest `json:"orders"`
+	Entry              *placeOrderRequest   `json:"entry"`
+	TakeProfitPrice    float64              `json:"takeProfitPrice"`
+	StopLossPrice      float64              `json:"stopLossPrice"`
+	StopLossLimitPrice float64              `json:"stopLossLimitPrice"`
+}
+
+// massQuoteRequest replaces every quote of the user on the product
+type massQuoteRequest struct {
+	ProductId string               `json:"productId"`
+	PostOnly  bool                 `json:"postOnly"`
+	Quotes    []*quoteLevelRequest `json:"quotes"`
+}
+
+type quoteLevelRequest struct {
+	Side  string  `json:"side"`
+	Price float64 `json:"price"`
+	Size  float64 `json:"size"`
+}
+
+type amendOrderRequest struct {
+	Size  float64 `json:"size"`
+	Price float64 `json:"price"`
 }
 
 type orderVo struct {
 	Id            string `json:"id"`
 	Price         string `json:"price"`
+	StopPrice     string `json:"stopPrice"`
 	Size          string `json:"size"`
 	Funds         string `json:"funds"`
 	ProductId     string `json:"productId"`
 	Side          string `json:"side"`
 	Type          string `json:"type"`
+	TimeInForce   string `json:"timeInForce"`
+	ExpireTime    string `json:"expireTime"`
+	PostOnly      bool   `json:"postOnly"`
+	Stp           string `json:"stp"`
+	DisplaySize   string `json:"displaySize"`
+	Hidden        bool   `json:"hidden"`
+	Quote         bool   `json:"quote,omitempty"`
+	PegType       string `json:"pegType,omitempty"`
+	PegOffset     string `json:"pegOffset,omitempty"`
+	TrailAmount   string `json:"trailAmount,omitempty"`
+	TrailPercent  string `json:"trailPercent,omitempty"`
+	GroupId       string `json:"groupId,omitempty"`
+	GroupRole     string `json:"groupRole,omitempty"`
 	CreatedAt     string `json:"createdAt"`
 	FillFees      string `json:"fillFees"`
 	FilledSize    string `json:"filledSize"`
 	ExecutedValue string `json:"executedValue"`
 	Status        string `json:"status"`
+	RejectReason  string `json:"rejectReason,omitempty"`
 	Settled       bool   `json:"settled"`
 }
 
+type orderGroupVo struct {
+	Id        string     `json:"id"`
+	ProductId string     `json:"productId"`
+	Type      string     `json:"type"`
+	Orders    []*orderVo `json:"orders"`
+	CreatedAt string     `json:"createdAt"`
+}
+
+type massQuoteVo struct {
+	ProductId string     `json:"productId"`
+	Orders    []*orderVo `json:"orders"`
+}
+
 const (
 	Level1 = "1"
 	Level2 = "2"
@@ -62,14 +155,53 @@ const (
 )
 
 type ProductVo struct {
-	Id             string `json:"string"`
+	Id             string `json:"id"`
 	BaseCurrency   string `json:"baseCurrency"`
 	QuoteCurrency  string `json:"quoteCurrency"`
 	BaseMinSize    string `json:"baseMinSize"`
 	BaseMaxSize string `json:"baseMaxSize"`
+	QuoteMinSize   string `json:"quoteMinSize"`
+	QuoteMaxSize   string `json:"quoteMaxSize"`
 	QuoteIncrement string `json:"quoteIncrement"`
 	BaseScale      int32  `json:"baseScale"`
 	QuoteScale     int32  `json:"quoteScale"`
+	Status         string `json:"status"`
+	TradingStatus  string `json:"tradingStatus"`
+
+	PriceBandPercent            string `json:"priceBandPercent"`
+	CircuitBreakerPercent       string `json:"circuitBreakerPercent"`
+	CircuitBreakerWindowSeconds int    `json:"circuitBreakerWindowSeconds"`
+	CircuitBreakerHaltSeconds   int    `json:"circuitBreakerHaltSeconds"`
+
+	MatchingAlgorithm string `json:"matchingAlgorithm"`
+}
+
+type tradingStatusRequest struct {
+	Status     string     `json:"status"`
+	AuctionEnd *time.Time `json:"auctionEnd"`
+}
+
+type productRequest struct {
+	BaseCurrency   string          `json:"baseCurrency"`
+	QuoteCurrency  string          `json:"quoteCurrency"`
+	BaseMinSize    decimal.Decimal `json:"baseMinSize"`
+	BaseMaxSize    decimal.Decimal `json:"baseMaxSize"`
+	QuoteMinSize   decimal.Decimal `json:"quoteMinSize"`
+	QuoteMaxSize   decimal.Decimal `json:"quoteMaxSize"`
+	QuoteIncrement float64         `json:"quoteIncrement"`
+	BaseScale      int32           `json:"baseScale"`
+	QuoteScale     int32           `json:"quoteScale"`
+
+	// Trading status of a new listing, open if empty
+	TradingStatus string `json:"tradingStatus"`
+
+	PriceBandPercent            decimal.Decimal `json:"priceBandPercent"`
+	CircuitBreakerPercent       decimal.Decimal `json:"circuitBreakerPercent"`
+	CircuitBreakerWindowSeconds int             `json:"circuitBreakerWindowSeconds"`
+	CircuitBreakerHaltSeconds   int             `json:"circuitBreakerHaltSeconds"`
+
+	// How trades are shared between the orders at a price, FIFO if empty
+	MatchingAlgorithm string `json:"matchingAlgorithm"`
 }
 
 type tradeVo struct {
@@ -77,7 +209,43 @@ type tradeVo struct {
 	TradeId int64  `json:"tradeId"`
 	Price   string `json:"price"`
 	Size    string `json:"size"`
-	Side    string `json:side"`
+	Side    string `json:"side"`
+}
+
+type tickerVo struct {
+	TradeId  int64  `json:"tradeId"`
+	Sequence int64  `json:"sequence"`
+	Time     string `json:"time"`
+	Price    string `json:"price"`
+	Size     string `json:"size"`
+	Side     string `json:"side"`
+	Bid      string `json:"bid"`
+	Ask      string `json:"ask"`
+	Volume   string `json:"volume"`
+}
+
+type statsVo struct {
+	Open        string `json:"open"`
+	High        string `json:"high"`
+	Low         string `json:"low"`
+	Last        string `json:"last"`
+	Volume      string `json:"volume"`
+	Volume30Day string `json:"volume30day"`
+}
+
+type fillVo struct {
+	Id        string `json:"id"`
+	TradeId   int64  `json:"tradeId"`
+	OrderId   string `json:"orderId"`
+	ProductId string `json:"productId"`
+	Price     string `json:"price"`
+	Size      string `json:"size"`
+	Fee       string `json:"fee"`
+	FeeRate   string `json:"feeRate"`
+	Liquidity string `json:"liquidity"`
+	Side      string `json:"side"`
+	Settled   bool   `json:"settled"`
+	CreatedAt string `json:"createdAt"`
 }
 
 type orderBookVo struct {
@@ -173,6 +341,48 @@ func newTradeVo(trade *entities.Trade) *tradeVo{
 	}
 }
 
+func newTickerVo(ticker *publisher.TickerMessage) *tickerVo{
+	return &tickerVo{
+		TradeId: ticker.TradeId, 
+		Sequence: ticker.Sequence, 
+		Time: ticker.Time, 
+		Price: ticker.Price, 
+		Size: ticker.LastSize, 
+		Side: ticker.Side, 
+		Bid: ticker.BestBid, 
+		Ask: ticker.BestAsk, 
+		Volume: ticker.Volume24h,
+	}
+}
+
+func newStatsVo(ticker *publisher.TickerMessage) *statsVo{
+	return &statsVo{
+		Open: ticker.Open24h, 
+		High: ticker.High24h, 
+		Low: ticker.Low24h, 
+		Last: ticker.Price, 
+		Volume: ticker.Volume24h, 
+		Volume30Day: ticker.Volume30d,
+	}
+}
+
+func newFillVo(fill *entities.Fill) *fillVo{
+	return &fillVo{
+		Id: utils.I64ToA(int64(fill.ID)), 
+		TradeId: fill.TradeId, 
+		OrderId: utils.I64ToA(fill.OrderId), 
+		ProductId: utils.I64ToA(fill.ProductId), 
+		Price: fill.Price.String(), 
+		Size: fill.Size.String(), 
+		Fee: fill.Fee.String(), 
+		FeeRate: fill.FeeRate.String(), 
+		Liquidity: fill.Liquidity, 
+		Side: fill.Side.String(), 
+		Settled: fill.Settled, 
+		CreatedAt: fill.CreatedAt.Format(time.RFC3339),
+	}
+}
+
 func newProductVo(product *entities.Product) *ProductVo{
 	return &ProductVo{
 		Id: strconv.Itoa(int(product.ID)), 
@@ -180,30 +390,92 @@ func newProductVo(product *entities.Product) *ProductVo{
 		QuoteCurrency: product.QuoteCurrency, 
 		BaseMinSize: product.BaseMinSize.String(), 
 		BaseMaxSize: product.BaseMaxSize.String(),
+		QuoteMinSize: product.QuoteMinSize.String(), 
+		QuoteMaxSize: product.QuoteMaxSize.String(), 
 		QuoteIncrement: utils.F64ToA(product.QuoteIncrement), 
 		BaseScale: product.BaseScale, 
 		QuoteScale: product.QuoteScale,
+		Status: string(product.Status),
+		TradingStatus: string(product.GetTradingStatus()),
+		PriceBandPercent: product.PriceLimits.PriceBandPercent.String(), 
+		CircuitBreakerPercent: product.PriceLimits.CircuitBreakerPercent.String(), 
+		CircuitBreakerWindowSeconds: product.PriceLimits.CircuitBreakerWindowSeconds, 
+		CircuitBreakerHaltSeconds: product.PriceLimits.CircuitBreakerHaltSeconds,
+		MatchingAlgorithm: string(product.GetMatchingAlgorithm()),
 	}
 }
 
 func newOrderVo(order *entities.Order) *orderVo{
+	var expireTime string 
+	if order.ExpireTime != nil{
+		expireTime = order.ExpireTime.Format(time.RFC3339)
+	}
+
+	var pegOffset string 
+	if len(order.PegType) > 0{
+		pegOffset = order.PegOffset.String()
+	}
+
+	var trailAmount, trailPercent string 
+	if order.TrailAmount.GreaterThan(decimal.Zero){
+		trailAmount = order.TrailAmount.String()
+	}
+	if order.TrailPercent.GreaterThan(decimal.Zero){
+		trailPercent = order.TrailPercent.String()
+	}
+
+	var groupId string 
+	if order.GroupId != 0{
+		groupId = utils.I64ToA(order.GroupId)
+	}
+
 	return &orderVo{
 		Id: utils.I64ToA(int64(order.ID)),  
 		Price: order.Price.String(), 
+		StopPrice: order.StopPrice.String(), 
 		Size: order.Size.String(), 
 		Funds: order.ExecutedValue.String(), 
 		ProductId: strconv.Itoa(order.ProductId), 
 		Side: order.Side.String(), 
 		Type: order.Type.String(), 
+		TimeInForce: string(order.TimeInForce), 
+		ExpireTime: expireTime, 
+		PostOnly: order.PostOnly, 
+		Stp: string(order.SelfTradePrevention), 
+		DisplaySize: order.DisplaySize.String(), 
+		Hidden: order.Hidden, 
+		Quote: order.Quote, 
+		PegType: string(order.PegType), 
+		PegOffset: pegOffset, 
+		TrailAmount: trailAmount, 
+		TrailPercent: trailPercent, 
+		GroupId: groupId, 
+		GroupRole: string(order.GroupRole), 
 		CreatedAt: order.CreatedAt.Format(time.RFC3339), 
 		FillFees: order.FillFees.String(), 
 		FilledSize: order.FilledSize.String(), 
 		ExecutedValue: order.ExecutedValue.String(),
 		Status: string(order.Status), 
+		RejectReason: string(order.RejectReason),
 		Settled: order.Settled,
 	}
 }
 
+func newOrderGroupVo(group *entities.OrderGroup, orders []*entities.Order) *orderGroupVo{
+	orderVos := []*orderVo{} 
+	for _, order := range orders{
+		orderVos = append(orderVos, newOrderVo(order)) 
+	}
+
+	return &orderGroupVo{
+		Id: utils.I64ToA(int64(group.ID)), 
+		ProductId: utils.I64ToA(group.ProductId), 
+		Type: string(group.Type), 
+		Orders: orderVos, 
+		CreatedAt: group.CreatedAt.Format(time.RFC3339), 
+	}
+}
+
 const BITCOIN_ICON_ADDRESS = "https://bitcoin.org/asset"
 
 func newAccountVo(account *entities.Account) *accountVo{
@@ -214,4 +486,41 @@ func newAccountVo(account *entities.Account) *accountVo{
 		Available: account.Available.String(), 
 		Hold: account.Hold.String(),
 	}
-}
\ No newline at end of file
+}
+
+// newLevel3OrderBookVo lists every order of the snapshot, the best price first
+// and the oldest order first within a price. The sequence is the one of the
+// last engine log in the snapshot, as carried by the messages of the full
+// channel
+func newLevel3OrderBookVo(snapshot *publisher.OrderBookFullSnapshot) *orderBookVo{
+	// the snapshot may be shared, sort a copy of its orders
+	orders := make([]matching.BookOrder, len(snapshot.Orders))
+	copy(orders, snapshot.Orders)
+	sort.Slice(orders, func(i, j int) bool{
+		if orders[i].Side != orders[j].Side{
+			return orders[i].Side == entities.SideBuy
+		}
+		if !orders[i].Price.Equal(orders[j].Price){
+			if orders[i].Side == entities.SideBuy{
+				return orders[i].Price.GreaterThan(orders[j].Price)
+			}
+			return orders[i].Price.LessThan(orders[j].Price)
+		}
+		return orders[i].OrderId < orders[j].OrderId
+	})
+
+	vo := &orderBookVo{
+		Sequence: utils.I64ToA(snapshot.LogSeq), 
+		Asks: [][3]interface{}{}, 
+		Bids: [][3]interface{}{},
+	}
+	for _, order := range orders{
+		entry := [3]interface{}{order.Price.String(), order.Size.String(), utils.I64ToA(order.OrderId)}
+		if order.Side == entities.SideBuy{
+			vo.Bids = append(vo.Bids, entry)
+		} else{
+			vo.Asks = append(vo.Asks, entry)
+		}
+	}
+	return vo 
+}
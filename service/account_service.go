@@ -22,6 +22,14 @@ func ExecuteBill(userId int64, currency string) error {
 	if err != nil {
 		return err
 	}
+	if account == nil {
+		account = &entities.Account{}
+	}
+	if account.ID == 0 {
+		// the first bill of the currency, such as a fee paid in it
+		account.UserId = userId
+		account.Currency = currency
+	}
 
 	bills, err := tx.GetUnsettledBillsByUserId(userId, currency)
 
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"time"
+
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/irononet/go-exchange/entities"
+	"github.com/irononet/go-exchange/store"
+	"github.com/irononet/go-exchange/store/mysql"
+	"github.com/shopspring/decimal"
+)
+
+// feeVolumeWindow is how far back the volume that sets the fee tier of a user goes
+const feeVolumeWindow = 30 * 24 * time.Hour
+
+// feeTierPeriod is how long the tier of a user holds once set, from the volume
+// traded in the window before the period starts
+const feeTierPeriod = time.Hour
+
+// FeeUserId owns the accounts of the exchange which collect the fees and pay the
+// maker rebates. No user has the id, the accounts only ever change by bills.
+const FeeUserId int64 = 0
+
+type feeRatesKey struct {
+	userId    int64
+	productId string
+	period    int64
+}
+
+type feeRates struct {
+	makerRate decimal.Decimal
+	takerRate decimal.Decimal
+}
+
+// feeRatesCache keeps the rates of the users who traded lately for the period,
+// so that the volume is summed once per user and period rather than per fill
+var feeRatesCache, _ = lru.New(10000)
+
+// GetFeeRates returns the maker and taker rates the user pays on the product for
+// a trade at the time, from the volume the user traded in the window before the
+// period of the time. Zero when the product has no fee schedule.
+func GetFeeRates(userId int64, productId string, at time.Time) (makerRate, takerRate decimal.Decimal, err error) {
+	period := at.Truncate(feeTierPeriod)
+	key := feeRatesKey{userId: userId, productId: productId, period: period.Unix()}
+	if cached, found := feeRatesCache.Get(key); found {
+		rates := cached.(feeRates)
+		return rates.makerRate, rates.takerRate, nil
+	}
+
+	tiers, err := mysql.SharedStore().GetFeeTiersByProductId(productId)
+	if err != nil {
+		return decimal.Zero, decimal.Zero, err
+	}
+
+	var rates feeRates
+	if len(tiers) > 0 {
+		volume, err := mysql.SharedStore().GetUserVolume(userId, productId, period.Add(-feeVolumeWindow), period)
+		if err != nil {
+			return decimal.Zero, decimal.Zero, err
+		}
+
+		tier := tiers[0]
+		for _, t := range tiers {
+			if volume.GreaterThanOrEqual(t.MinVolume) {
+				tier = t
+			}
+		}
+		rates = feeRates{makerRate: tier.MakerRate, takerRate: tier.TakerRate}
+	}
+
+	feeRatesCache.Add(key, rates)
+	return rates.makerRate, rates.takerRate, nil
+}
+
+func GetFeeTiersByProductId(productId string) ([]*entities.FeeTier, error) {
+	return mysql.SharedStore().GetFeeTiersByProductId(productId)
+}
+
+// payableRebate caps a maker rebate to what the fee account holds in the
+// currency, counting the fees and rebates not settled yet. The account is
+// locked, so that the rebates paid at the same time can't overdraw it together.
+// No rebate is paid before the account exists.
+func payableRebate(db store.Store, currency string, rebate decimal.Decimal) (decimal.Decimal, error) {
+	account, err := db.GetAccountForUpdate(FeeUserId, currency)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	if account == nil || account.ID == 0 {
+		return decimal.Zero, nil
+	}
+
+	bills, err := db.GetUnsettledBillsByUserId(FeeUserId, currency)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	balance := account.Available
+	for _, bill := range bills {
+		balance = balance.Add(bill.Avaiable)
+	}
+	if balance.LessThanOrEqual(decimal.Zero) {
+		return decimal.Zero, nil
+	}
+	return decimal.Min(rebate, balance), nil
+}
@@ -21,3 +21,7 @@ func AddFills(fills []*entities.Fill) error {
 
 	return nil
 }
+
+func GetFillsByUserId(userId int64, productId string, orderId int64, beforeId, afterId int64, limit int) ([]*entities.Fill, error) {
+	return mysql.SharedStore().GetFillsByUserId(userId, productId, orderId, beforeId, afterId, limit)
+}
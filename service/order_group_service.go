@@ -0,0 +1,166 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/irononet/go-exchange/entities"
+	"github.com/irononet/go-exchange/store/mysql"
+	"github.com/shopspring/decimal"
+)
+
+// OrderGroupLeg is an order placed as part of a group
+type OrderGroupLeg struct {
+	ClientUid string
+	Type      entities.OrderType
+	Side      entities.Side
+	Size      decimal.Decimal
+	Price     decimal.Decimal
+	Funds     decimal.Decimal
+	Opts      OrderOptions
+}
+
+// PlaceOcoGroup places legs which cancel each other as soon as one of them
+// trades or ends. The legs are on the same side and hold once for the group,
+// what the largest of them needs.
+func PlaceOcoGroup(userId int64, productId string, legs []OrderGroupLeg) (*entities.OrderGroup, []*entities.Order, error) {
+	if len(legs) != 2 {
+		return nil, nil, errors.New("an OCO group has two legs")
+	}
+	if legs[0].Side != legs[1].Side {
+		return nil, nil, errors.New("the legs of an OCO group are on the same side")
+	}
+
+	product, err := getProductAcceptingOrders(productId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	group := &entities.OrderGroup{
+		UserId:    userId,
+		ProductId: int64(product.ID),
+		Type:      entities.OrderGroupTypeOco,
+		OpenLegs:  len(legs),
+	}
+
+	var orders []*entities.Order
+	for _, leg := range legs {
+		if leg.Type == entities.MARKET_ORDER {
+			return nil, nil, errors.New("market orders can't be legs of an OCO group")
+		}
+		if leg.Opts.TimeInForce == entities.TimeInForceIOC || leg.Opts.TimeInForce == entities.TimeInForceFOK {
+			return nil, nil, fmt.Errorf("%v orders can't be legs of an OCO group", leg.Opts.TimeInForce)
+		}
+
+		order, holdCurrency, holdSize, err := newOrder(product, userId, leg.ClientUid, leg.Type, leg.Side,
+			leg.Size, leg.Price, leg.Funds, leg.Opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		order.GroupRole = entities.OrderGroupRoleLeg
+		orders = append(orders, order)
+
+		group.HoldCurrency = holdCurrency
+		group.Hold = decimal.Max(group.Hold, holdSize)
+	}
+
+	return group, orders, addOrderGroup(userId, group, orders, "", decimal.Zero)
+}
+
+// PlaceBracketGroup places a limit buy entry with a take-profit limit sell and
+// a stop-loss sell, a stop-limit if stopLimitPrice isn't zero. Once the entry
+// is done the exits are armed for the size it filled, sell what it bought, and
+// cancel each other like the legs of an OCO group.
+func PlaceBracketGroup(userId int64, productId string, entry OrderGroupLeg,
+	takeProfitPrice, stopPrice, stopLimitPrice decimal.Decimal) (*entities.OrderGroup, []*entities.Order, error) {
+
+	if entry.Type != entities.LIMIT_ORDER || entry.Side != entities.SideBuy {
+		return nil, nil, errors.New("the entry of a bracket is a limit buy")
+	}
+	if len(entry.Opts.PegType) > 0 {
+		return nil, nil, errors.New("the entry of a bracket can't be pegged")
+	}
+	if takeProfitPrice.LessThanOrEqual(stopPrice) {
+		return nil, nil, fmt.Errorf("take profit price %v not above stop price %v", takeProfitPrice, stopPrice)
+	}
+
+	product, err := getProductAcceptingOrders(productId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entryOrder, holdCurrency, holdSize, err := newOrder(product, userId, entry.ClientUid, entry.Type, entry.Side,
+		entry.Size, entry.Price, entry.Funds, entry.Opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	entryOrder.GroupRole = entities.OrderGroupRoleEntry
+
+	stp := entryOrder.SelfTradePrevention
+	takeProfit, _, _, err := newOrder(product, userId, "", entities.LIMIT_ORDER, entities.SideSell,
+		entryOrder.Size, takeProfitPrice, decimal.Zero, OrderOptions{SelfTradePrevention: stp})
+	if err != nil {
+		return nil, nil, err
+	}
+	takeProfit.GroupRole = entities.OrderGroupRoleTakeProfit
+
+	stopLossType, stopLossPrice := entities.STOP_MARKET_ORDER, decimal.Zero
+	if !stopLimitPrice.IsZero() {
+		stopLossType, stopLossPrice = entities.STOP_LIMIT_ORDER, stopLimitPrice
+	}
+	stopLoss, _, _, err := newOrder(product, userId, "", stopLossType, entities.SideSell,
+		entryOrder.Size, stopLossPrice, decimal.Zero, OrderOptions{StopPrice: stopPrice, SelfTradePrevention: stp})
+	if err != nil {
+		return nil, nil, err
+	}
+	stopLoss.GroupRole = entities.OrderGroupRoleStopLoss
+
+	// The exits are held from what the entry buys
+	group := &entities.OrderGroup{
+		UserId:       userId,
+		ProductId:    int64(product.ID),
+		Type:         entities.OrderGroupTypeBracket,
+		HoldCurrency: product.BaseCurrency,
+		OpenLegs:     2,
+	}
+	orders := []*entities.Order{entryOrder, takeProfit, stopLoss}
+	return group, orders, addOrderGroup(userId, group, orders, holdCurrency, holdSize)
+}
+
+// addOrderGroup holds for the group and for a bracket entry, which holds for
+// itself, then adds the group and its orders in a single transaction
+func addOrderGroup(userId int64, group *entities.OrderGroup, orders []*entities.Order,
+	entryHoldCurrency string, entryHold decimal.Decimal) error {
+	db, err := mysql.SharedStore().BeginTx()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Rollback() }()
+
+	if group.Hold.GreaterThan(decimal.Zero) {
+		err = HoldBalance(db, userId, group.HoldCurrency, group.Hold, entities.BillTypeTrade)
+		if err != nil {
+			return err
+		}
+	}
+	if entryHold.GreaterThan(decimal.Zero) {
+		err = HoldBalance(db, userId, entryHoldCurrency, entryHold, entities.BillTypeTrade)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = db.AddOrderGroup(group)
+	if err != nil {
+		return err
+	}
+
+	for _, order := range orders {
+		order.GroupId = int64(group.ID)
+		err = db.AddOrder(order)
+		if err != nil {
+			return err
+		}
+	}
+	return db.CommitTx()
+}
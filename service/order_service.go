@@ -5,53 +5,270 @@ import (
 	"fmt"
 	"log"
 	"strconv"
+	"time"
 
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/irononet/go-exchange/entities"
+	"github.com/irononet/go-exchange/store"
 	"github.com/irononet/go-exchange/store/mysql"
 	"github.com/shopspring/decimal"
 )
 
+// defaultMarketProtectionPercent is how far from the last trade price the hold
+// of a market buy by size or a market sell by funds is priced, on products
+// without a price band
+var defaultMarketProtectionPercent = decimal.New(10, 0)
+
+// OrderOptions holds the optional instructions of an order placement
+type OrderOptions struct {
+	// Price the trade price has to cross before a stop order is activated
+	StopPrice decimal.Decimal
+
+	// How long the order stays on the book, GTC if empty
+	TimeInForce entities.TimeInForce
+
+	// Time at which a GTT order is cancelled
+	ExpireTime time.Time
+
+	// Reject the order instead of letting it take liquidity
+	PostOnly bool
+
+	// What happens when the order would trade with an order of the same
+	// user, decrement and cancel if empty
+	SelfTradePrevention entities.SelfTradePrevention
+
+	// Size shown on the book by an iceberg order, the whole size if zero
+	DisplaySize decimal.Decimal
+
+	// Keep the order off the book feeds entirely
+	Hidden bool
+
+	// Price a limit order tracks instead of resting at its price, which then
+	// caps a buy and floors a sell
+	PegType entities.PegType
+
+	// Added to the tracked price of a pegged order, may be negative
+	PegOffset decimal.Decimal
+
+	// Turn a stop market order into a trailing stop, whose stop price follows
+	// the best trade price at this distance. Only one of them may be set
+	TrailAmount  decimal.Decimal
+	TrailPercent decimal.Decimal
+}
+
 func PlaceOrder(userId int64, clientUid string, productId string, orderType entities.OrderType,
-	side entities.Side, size, price, funds decimal.Decimal) (*entities.Order, error) {
+	side entities.Side, size, price, funds decimal.Decimal, opts OrderOptions) (*entities.Order, error) {
+
+	product, err := getProductAcceptingOrders(productId)
+	if err != nil {
+		return nil, err
+	}
+
+	order, holdCurrency, holdSize, err := newOrder(product, userId, clientUid, orderType, side, size, price, funds, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// transaction
+	db, err := mysql.SharedStore().BeginTx()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = db.Rollback() }()
+
+	err = HoldBalance(db, userId, holdCurrency, holdSize, entities.BillTypeTrade)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.AddOrder(order)
+	if err != nil {
+		return nil, err
+	}
 
+	return order, db.CommitTx()
+}
+
+func getProductAcceptingOrders(productId string) (*entities.Product, error) {
 	product, err := GetProductById(productId)
 	if err != nil {
 		return nil, err
 	}
 
 	if product == nil {
-		return nil, fmt.Errorf("size %v less than base min size %v", size, product.BaseMinSize)
+		return nil, fmt.Errorf("product not found: %v", productId)
 	}
+	if !product.Status.AcceptsOrders() {
+		return nil, fmt.Errorf("product %v is %v", productId, product.Status)
+	}
+	return product, nil
+}
 
-	if orderType == entities.LIMIT_ORDER {
-		size = size.Round(product.BaseScale)
-		if size.LessThan(product.BaseMinSize) {
-			return nil, fmt.Errorf("size %v less than base min size %v", size, product.BaseMinSize)
+// newOrder validates an order against the rules of the product and returns it
+// with what it has to hold.
+func newOrder(product *entities.Product, userId int64, clientUid string, orderType entities.OrderType,
+	side entities.Side, size, price, funds decimal.Decimal, opts OrderOptions) (*entities.Order, string, decimal.Decimal, error) {
+
+	// Orders off the rules of the product are rejected rather than rounded,
+	// so what trades is what was sent
+	rules := NewProductRules(product)
+	if orderType == entities.LIMIT_ORDER || orderType == entities.STOP_LIMIT_ORDER {
+		if err := rules.ValidateLimit(size, price); err != nil {
+			return nil, "", decimal.Zero, err
 		}
-		price = price.Round(product.QuoteScale)
-		if price.LessThan(decimal.Zero) {
-			return nil, fmt.Errorf("price %v less than 0", price)
+		if orderType == entities.LIMIT_ORDER {
+			if err := validatePriceBand(rules, strconv.Itoa(int(product.ID)), price); err != nil {
+				return nil, "", decimal.Zero, err
+			}
 		}
 		funds = size.Mul(price)
-	} else if orderType == entities.MARKET_ORDER {
-		if side == entities.SideBuy {
-			size = decimal.Zero
-			price = decimal.Zero
-			funds = funds.Round(product.QuoteScale)
-			if funds.LessThan(product.QuoteMinSize) {
-				return nil, fmt.Errorf("funds %v less than quote min size %v", funds, product.QuoteMinSize)
+	} else if orderType == entities.MARKET_ORDER || orderType == entities.STOP_MARKET_ORDER {
+		// A market order is specified by size or by funds on either side. A
+		// buy by size holds the funds it takes at the protection price, and
+		// a sell by funds the size, the engine never trades beyond them.
+		price = decimal.Zero
+		if size.GreaterThan(decimal.Zero) && funds.GreaterThan(decimal.Zero) {
+			return nil, "", decimal.Zero, errors.New("market orders are specified by either size or funds")
+		}
+		if funds.GreaterThan(decimal.Zero) || (side == entities.SideBuy && size.IsZero()) {
+			if err := rules.ValidateNotional("funds", funds); err != nil {
+				return nil, "", decimal.Zero, err
+			}
+			if side == entities.SideSell {
+				protectionPrice, err := marketProtectionPrice(product, side)
+				if err != nil {
+					return nil, "", decimal.Zero, err
+				}
+				size = funds.Div(protectionPrice).RoundCeil(product.BaseScale)
 			}
 		} else {
-			size = size.Round(product.BaseScale)
-			if size.LessThan(product.BaseMinSize) {
-				return nil, fmt.Errorf("size %v less than base min size %v", size, product.BaseMinSize)
+			if err := rules.ValidateSize("size", size); err != nil {
+				return nil, "", decimal.Zero, err
+			}
+			if side == entities.SideBuy {
+				protectionPrice, err := marketProtectionPrice(product, side)
+				if err != nil {
+					return nil, "", decimal.Zero, err
+				}
+				funds = size.Mul(protectionPrice).RoundCeil(product.QuoteScale)
 			}
-			price = decimal.Zero
-			funds = decimal.Zero
 		}
 	} else {
-		return nil, errors.New("unknown order type")
+		return nil, "", decimal.Zero, errors.New("unknown order type")
+	}
+
+	timeInForce := opts.TimeInForce
+	if len(timeInForce) == 0 {
+		timeInForce = entities.TimeInForceGTC
+	}
+	if _, err := entities.NewTimeInForceFromString(string(timeInForce)); err != nil {
+		return nil, "", decimal.Zero, err
+	}
+
+	var expireTime *time.Time
+	if timeInForce == entities.TimeInForceGTT {
+		if orderType == entities.MARKET_ORDER || orderType == entities.STOP_MARKET_ORDER {
+			return nil, "", decimal.Zero, errors.New("GTT is not allowed for market orders")
+		}
+		if !opts.ExpireTime.After(time.Now()) {
+			return nil, "", decimal.Zero, fmt.Errorf("expire time %v is not in the future", opts.ExpireTime)
+		}
+		expireTime = &opts.ExpireTime
+	} else if !opts.ExpireTime.IsZero() {
+		return nil, "", decimal.Zero, fmt.Errorf("expire time is only allowed for %v orders", entities.TimeInForceGTT)
+	}
+
+	if opts.PostOnly {
+		if orderType != entities.LIMIT_ORDER && orderType != entities.STOP_LIMIT_ORDER {
+			return nil, "", decimal.Zero, errors.New("post only is only allowed for limit orders")
+		}
+		if timeInForce == entities.TimeInForceIOC || timeInForce == entities.TimeInForceFOK {
+			return nil, "", decimal.Zero, fmt.Errorf("post only is not allowed for %v orders", timeInForce)
+		}
+	}
+
+	displaySize := decimal.Zero
+	if opts.DisplaySize.GreaterThan(decimal.Zero) || opts.Hidden {
+		if orderType != entities.LIMIT_ORDER && orderType != entities.STOP_LIMIT_ORDER {
+			return nil, "", decimal.Zero, errors.New("display size and hidden are only allowed for limit orders")
+		}
+		if timeInForce == entities.TimeInForceIOC || timeInForce == entities.TimeInForceFOK {
+			return nil, "", decimal.Zero, fmt.Errorf("display size and hidden are not allowed for %v orders", timeInForce)
+		}
+	}
+	if opts.DisplaySize.GreaterThan(decimal.Zero) {
+		if opts.Hidden {
+			return nil, "", decimal.Zero, errors.New("hidden orders have no display size")
+		}
+		displaySize = opts.DisplaySize
+		if err := rules.ValidateSize("displaySize", displaySize); err != nil {
+			return nil, "", decimal.Zero, err
+		}
+		if displaySize.GreaterThanOrEqual(size) {
+			// nothing to hide
+			displaySize = decimal.Zero
+		}
+	}
+
+	stp := opts.SelfTradePrevention
+	if len(stp) == 0 {
+		stp = entities.SelfTradePreventionDecrementAndCancel
+	}
+	if _, err := entities.NewSelfTradePreventionFromString(string(stp)); err != nil {
+		return nil, "", decimal.Zero, err
+	}
+
+	// Pegged orders only add liquidity, the engine prices them so that they
+	// never cross the book
+	pegOffset := decimal.Zero
+	if len(opts.PegType) > 0 {
+		if _, err := entities.NewPegTypeFromString(string(opts.PegType)); err != nil {
+			return nil, "", decimal.Zero, err
+		}
+		if orderType != entities.LIMIT_ORDER {
+			return nil, "", decimal.Zero, errors.New("peg is only allowed for limit orders")
+		}
+		if timeInForce == entities.TimeInForceIOC || timeInForce == entities.TimeInForceFOK {
+			return nil, "", decimal.Zero, fmt.Errorf("peg is not allowed for %v orders", timeInForce)
+		}
+		pegOffset = opts.PegOffset
+		if !pegOffset.Mod(rules.TickSize()).IsZero() {
+			return nil, "", decimal.Zero, &ProductRuleError{ProductRuleTickSize, "pegOffset", pegOffset, rules.TickSize()}
+		}
+	} else if !opts.PegOffset.IsZero() {
+		return nil, "", decimal.Zero, errors.New("peg offset is only allowed for pegged orders")
+	}
+
+	// The engine sets the stop price of a trailing stop from the trade price
+	trailing := opts.TrailAmount.GreaterThan(decimal.Zero) || opts.TrailPercent.GreaterThan(decimal.Zero)
+	if trailing {
+		if orderType != entities.STOP_MARKET_ORDER {
+			return nil, "", decimal.Zero, errors.New("trailing is only allowed for stop market orders")
+		}
+		if opts.TrailAmount.GreaterThan(decimal.Zero) && opts.TrailPercent.GreaterThan(decimal.Zero) {
+			return nil, "", decimal.Zero, errors.New("trail amount and trail percent can't both be set")
+		}
+		if !opts.StopPrice.IsZero() {
+			return nil, "", decimal.Zero, errors.New("trailing stop orders have no stop price")
+		}
+		if opts.TrailAmount.GreaterThan(decimal.Zero) {
+			if err := rules.ValidatePrice("trailAmount", opts.TrailAmount); err != nil {
+				return nil, "", decimal.Zero, err
+			}
+		}
+		if opts.TrailPercent.GreaterThanOrEqual(decimal.New(100, 0)) {
+			return nil, "", decimal.Zero, fmt.Errorf("trail percent %v not below 100", opts.TrailPercent)
+		}
+	} else if opts.TrailAmount.IsNegative() || opts.TrailPercent.IsNegative() {
+		return nil, "", decimal.Zero, errors.New("trail amount and trail percent can't be negative")
+	}
+
+	stopPrice := decimal.Zero
+	if orderType.IsStop() && !trailing {
+		stopPrice = opts.StopPrice
+		if err := rules.ValidatePrice("stopPrice", stopPrice); err != nil {
+			return nil, "", decimal.Zero, err
+		}
 	}
 
 	var holdCurrency string
@@ -63,35 +280,166 @@ func PlaceOrder(userId int64, clientUid string, productId string, orderType enti
 	}
 
 	order := &entities.Order{
-		ClientUuid: clientUid,
-		UserId:     int(userId),
-		ProductId:  int(product.ID),
-		Side:       side,
-		Size:       size,
-		Funds:      funds,
-		Price:      price,
-		Status:     entities.OrderStatusNew,
-		Type:       orderType,
+		ClientUuid:  clientUid,
+		UserId:      int(userId),
+		ProductId:   int(product.ID),
+		Side:        side,
+		Size:        size,
+		Funds:       funds,
+		Price:       price,
+		StopPrice:   stopPrice,
+		Status:      entities.OrderStatusNew,
+		Type:        orderType,
+		TimeInForce: timeInForce,
+		ExpireTime:  expireTime,
+		PostOnly:    opts.PostOnly,
+
+		DisplaySize: displaySize,
+		Hidden:      opts.Hidden,
+
+		SelfTradePrevention: stp,
+
+		PegType:   opts.PegType,
+		PegOffset: pegOffset,
+
+		TrailAmount:  opts.TrailAmount,
+		TrailPercent: opts.TrailPercent,
 	}
+	return order, holdCurrency, holdSize, nil
+}
 
-	// transaction
+// AmendOrder holds what an amend of the order to size and price may need, and
+// returns the amend to submit to the matching engine. Size is the new size of
+// the whole order, including what is already filled. The excess is released
+// once the amend is settled.
+func AmendOrder(orderId int64, size, price decimal.Decimal) (*entities.Order, error) {
 	db, err := mysql.SharedStore().BeginTx()
 	if err != nil {
 		return nil, err
 	}
 	defer func() { _ = db.Rollback() }()
 
-	err = HoldBalance(db, userId, holdCurrency, holdSize, entities.BillTypeTrade)
+	order, err := db.GetOrderByIdForUpdate(orderId)
 	if err != nil {
 		return nil, err
 	}
+	if order == nil {
+		return nil, fmt.Errorf("order not found: %v", orderId)
+	}
+	if order.Status != entities.OrderStatusNew && order.Status != entities.OrderStatusOpen {
+		return nil, fmt.Errorf("order status invalid: %v %v", orderId, order.Status)
+	}
+	if order.Type != entities.LIMIT_ORDER {
+		return nil, errors.New("only limit orders can be amended")
+	}
+	if len(order.PegType) > 0 {
+		return nil, errors.New("pegged orders can't be amended")
+	}
+	if order.GroupId != 0 {
+		return nil, errors.New("orders of a group can't be amended")
+	}
+	if order.AmendPending {
+		return nil, fmt.Errorf("order %v has an amend in progress", orderId)
+	}
 
-	err = db.AddOrder(order)
+	product, err := GetProductById(strconv.Itoa(order.ProductId))
 	if err != nil {
 		return nil, err
 	}
+	if product == nil {
+		return nil, fmt.Errorf("product not found: %v", order.ProductId)
+	}
+	if !product.Status.AcceptsOrders() {
+		return nil, fmt.Errorf("product %v is %v", order.ProductId, product.Status)
+	}
 
-	return order, db.CommitTx()
+	rules := NewProductRules(product)
+	if err := rules.ValidateLimit(size, price); err != nil {
+		return nil, err
+	}
+	if !price.Equal(order.Price) {
+		if err := validatePriceBand(rules, strconv.Itoa(order.ProductId), price); err != nil {
+			return nil, err
+		}
+	}
+	if size.LessThanOrEqual(order.FilledSize) {
+		return nil, fmt.Errorf("size %v less than or equal to filled size %v", size, order.FilledSize)
+	}
+
+	// Until the engine applies the amend the order keeps trading at its
+	// current price, so a buy holds for the higher of both prices
+	var holdCurrency string
+	var holdSize decimal.Decimal
+	if order.Side == entities.SideBuy {
+		funds := order.ExecutedValue.Add(size.Sub(order.FilledSize).Mul(decimal.Max(order.Price, price)))
+		if funds.GreaterThan(order.Funds) {
+			holdCurrency, holdSize = product.QuoteCurrency, funds.Sub(order.Funds)
+			order.Funds = funds
+		}
+	} else if size.GreaterThan(order.Size) {
+		holdCurrency, holdSize = product.BaseCurrency, size.Sub(order.Size)
+		order.Size = size
+	}
+
+	if holdSize.GreaterThan(decimal.Zero) {
+		err = HoldBalance(db, int64(order.UserId), holdCurrency, holdSize, entities.BillTypeTrade)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	order.AmendPending = true
+	order.AmendHold = holdSize
+	err = db.UpdateOrder(order)
+	if err != nil {
+		return nil, err
+	}
+
+	amend := *order
+	amend.Status = entities.OrderStatusAmending
+	amend.Size = size
+	amend.Price = price
+	return &amend, db.CommitTx()
+}
+
+// validatePriceBand checks a limit price against the band around the last trade
+// price of the product. The matching engine checks it again against its own
+// last trade, or the mid price before the first trade.
+func validatePriceBand(rules *ProductRules, productId string, price decimal.Decimal) error {
+	trade, err := GetLastTradeByProductId(productId)
+	if err != nil {
+		return err
+	}
+	if trade == nil {
+		return nil
+	}
+	return rules.ValidatePriceBand("price", price, trade.Price)
+}
+
+// marketProtectionPrice is the worst price a market buy by size or a market sell
+// by funds is held for, the edge of the price band around the last trade price.
+// The products without a price band use the default protection band.
+func marketProtectionPrice(product *entities.Product, side entities.Side) (decimal.Decimal, error) {
+	trade, err := GetLastTradeByProductId(strconv.Itoa(int(product.ID)))
+	if err != nil {
+		return decimal.Zero, err
+	}
+	if trade == nil {
+		return decimal.Zero, fmt.Errorf("no trade price to hold a market %v order against yet", side)
+	}
+
+	limits := product.PriceLimits
+	if limits.PriceBandPercent.LessThanOrEqual(decimal.Zero) {
+		limits.PriceBandPercent = defaultMarketProtectionPercent
+	}
+	low, high, _ := limits.PriceBand(trade.Price)
+	if side == entities.SideBuy {
+		return high, nil
+	}
+	if low.LessThanOrEqual(decimal.Zero) {
+		return decimal.Zero, fmt.Errorf("no protection price for a market %v order", side)
+	}
+	return low, nil
 }
 
 func UpdateOrderStatus(orderId int64, oldStatus, newStatus entities.OrderStatus) (bool, error) {
@@ -113,7 +461,18 @@ func ExecuteFill(orderId int64) error {
 	if order == nil {
 		return fmt.Errorf("order not found: %v", orderId)
 	}
-	if order.Status == entities.OrderStatusFilled || order.Status == entities.OrderStatusCancelled {
+	if order.Status == entities.OrderStatusFilled || order.Status == entities.OrderStatusCancelled ||
+		order.Status == entities.OrderStatusRejected {
+		// The engine rejects a replay of an order it can no longer tell from
+		// a new one, the rejection of an order already done changes nothing
+		// but has to be settled
+		settled, err := settleLateRejections(db, orderId)
+		if err != nil {
+			return err
+		}
+		if settled {
+			return db.CommitTx()
+		}
 		return fmt.Errorf("order status invalid: %v %v", orderId, order.Status)
 	}
 
@@ -132,39 +491,172 @@ func ExecuteFill(orderId int64) error {
 		return nil
 	}
 
+	// Legs of a group settle against the hold of the group, the entry of a
+	// bracket keeps what it buys held for its exits until they are all done
+	var group *entities.OrderGroup
+	if order.GroupId != 0 {
+		group, err = db.GetOrderGroupByIdForUpdate(order.GroupId)
+		if err != nil {
+			return err
+		}
+		if group == nil {
+			return fmt.Errorf("order group not found: %v", order.GroupId)
+		}
+	}
+	groupHeld := group != nil && order.GroupRole != entities.OrderGroupRoleEntry
+
 	var bills []*entities.Bill
 	for _, fill := range fills {
 		fill.Settled = true
 		notes := fmt.Sprintf("%v-%v", fill.OrderId, fill.ID)
 
-		if !fill.Done {
+		if fill.Amend && len(fill.RejectReason) > 0 {
+			// The engine refused the amend, the order stays as it was
+			bill, err := releaseAmendHold(db, order, product, notes)
+			if err != nil {
+				return err
+			}
+			if bill != nil {
+				bills = append(bills, bill)
+			}
+		} else if fill.Amend {
+			// The amend is applied, release what was held for it but isn't
+			// needed by the rest of the order
+			var excessCurrency string
+			var excess decimal.Decimal
+			if order.Side == entities.SideBuy {
+				funds := order.ExecutedValue.Add(fill.Size.Sub(order.FilledSize).Mul(fill.Price))
+				excessCurrency, excess = product.QuoteCurrency, order.Funds.Sub(funds)
+				order.Funds = funds
+			} else {
+				excessCurrency, excess = product.BaseCurrency, order.Size.Sub(fill.Size)
+				order.Funds = fill.Size.Mul(fill.Price)
+			}
+			if excess.LessThan(decimal.Zero) {
+				return fmt.Errorf("amend of order %v needs %v more than held", orderId, excess.Neg())
+			}
+
+			order.Size = fill.Size
+			order.Price = fill.Price
+			order.AmendPending = false
+			order.AmendHold = decimal.Zero
+
+			if excess.GreaterThan(decimal.Zero) {
+				bill, err := AddDelayBill(db, int64(order.UserId), excessCurrency, excess, excess.Neg(), entities.BillTypeTrade, notes)
+				if err != nil {
+					return err
+				}
+				bills = append(bills, bill)
+			}
+		} else if !fill.Done {
 			executedValue := fill.Size.Mul(fill.Price)
 			order.ExecutedValue = order.ExecutedValue.Add(executedValue)
 			order.FilledSize = order.FilledSize.Add(fill.Size)
 
+			// The rate is the one of the tier of the user when the fill was
+			// made, however late it's settled
+			makerRate, takerRate, err := GetFeeRates(int64(order.UserId), strconv.Itoa(order.ProductId), fill.CreatedAt)
+			if err != nil {
+				return err
+			}
+			fill.FeeRate = takerRate
+			if fill.Liquidity == "M" {
+				fill.FeeRate = makerRate
+			}
+
+			// The fee is paid in the currency the fill brings in, so it never
+			// needs more than what was held
+			var holdCurrency, receiveCurrency string
+			var holdSize, receiveSize decimal.Decimal
 			if order.Side == entities.SideBuy {
-				bill, err := AddDelayBill(db, int64(order.UserId), product.BaseCurrency, fill.Size, decimal.Zero, entities.BillTypeTrade, notes)
+				holdCurrency, holdSize = product.QuoteCurrency, executedValue
+				receiveCurrency, receiveSize = product.BaseCurrency, fill.Size
+				fill.Fee = fill.Size.Mul(fill.FeeRate).Round(product.BaseScale)
+			} else {
+				holdCurrency, holdSize = product.BaseCurrency, fill.Size
+				receiveCurrency, receiveSize = product.QuoteCurrency, executedValue
+				fill.Fee = executedValue.Mul(fill.FeeRate).Round(product.QuoteScale)
+			}
+
+			// A maker rebate is paid out of the fees collected, never more
+			if fill.Fee.LessThan(decimal.Zero) {
+				rebate, err := payableRebate(db, receiveCurrency, fill.Fee.Neg())
 				if err != nil {
 					return err
 				}
-				bills = append(bills, bill)
-			} else {
-				bill, err := AddDelayBill(db, int64(order.UserId), product.QuoteCurrency, executedValue, decimal.Zero, entities.BillTypeTrade, notes)
+				fill.Fee = rebate.Neg()
+			}
+			order.FillFees = order.FillFees.Add(fill.Fee)
+
+			bill, err := AddDelayBill(db, int64(order.UserId), holdCurrency, decimal.Zero, holdSize.Neg(), entities.BillTypeTrade, notes)
+			if err != nil {
+				return err
+			}
+			bills = append(bills, bill)
+
+			receiveAvailable, receiveHold := receiveSize.Sub(fill.Fee), decimal.Zero
+			if groupHeld {
+				group.Hold = group.Hold.Sub(holdSize)
+			} else if group != nil && group.OpenLegs > 0 {
+				receiveAvailable, receiveHold = decimal.Zero, receiveSize.Sub(fill.Fee)
+				group.Hold = group.Hold.Add(receiveHold)
+			}
+
+			bill, err = AddDelayBill(db, int64(order.UserId), receiveCurrency, receiveAvailable, receiveHold, entities.BillTypeTrade, notes)
+			if err != nil {
+				return err
+			}
+			bills = append(bills, bill)
+
+			if !fill.Fee.IsZero() {
+				bill, err = AddDelayBill(db, FeeUserId, receiveCurrency, fill.Fee, decimal.Zero, entities.BillTypeFee, notes)
 				if err != nil {
 					return err
 				}
 				bills = append(bills, bill)
 			}
 		} else {
-			if fill.DoneReason == entities.DoneReasonCancelled {
-				order.Status = entities.OrderStatusCancelled
-			} else if fill.DoneReason == entities.DoneReasonFilled {
-				order.Status = entities.OrderStatusFilled
+			if len(fill.RejectReason) > 0 {
+				// Only an order the engine never took is rejected, the
+				// rejection of one it took is a late replay which changes
+				// nothing
+				if order.Status != entities.OrderStatusNew || !order.FilledSize.IsZero() {
+					break
+				}
+				order.Status = entities.OrderStatusRejected
+				order.RejectReason = fill.RejectReason
 			} else {
-				log.Fatalf("unkown done reason: %v", fill.DoneReason)
+				switch fill.DoneReason {
+				case entities.DoneReasonCancelled, entities.DoneReasonExpired, entities.DoneReasonPostOnly,
+					entities.DoneReasonSelfTrade, entities.DoneReasonTradingStatus, entities.DoneReasonPriceBand,
+					entities.DoneReasonDuplicate:
+					order.Status = entities.OrderStatusCancelled
+				case entities.DoneReasonFilled:
+					order.Status = entities.OrderStatusFilled
+				default:
+					log.Fatalf("unkown done reason: %v", fill.DoneReason)
+				}
 			}
 
-			if order.Side == entities.SideBuy {
+			// What was held for an amend in flight is in the funds or the
+			// size of the order, it's released with the rest
+			order.AmendPending = false
+			order.AmendHold = decimal.Zero
+
+			if groupHeld {
+				// The last leg done releases what the group still holds
+				group.OpenLegs--
+				if group.OpenLegs == 0 && !group.Hold.IsZero() {
+					bill, err := AddDelayBill(db, int64(order.UserId), group.HoldCurrency, group.Hold, group.Hold.Neg(), entities.BillTypeTrade, notes)
+					if err != nil {
+						return err
+					}
+					bills = append(bills, bill)
+					group.Hold = decimal.Zero
+				}
+			} else if order.Side == entities.SideBuy {
+				// What was held and not spent, which for a market buy by size
+				// includes the margin of the protection price
 				remainingFunds := order.Funds.Sub(order.ExecutedValue)
 				if remainingFunds.GreaterThan(decimal.Zero) {
 					bill, err := AddDelayBill(db, int64(order.UserId), product.QuoteCurrency, remainingFunds, remainingFunds.Neg(), entities.BillTypeTrade, notes)
@@ -174,6 +666,8 @@ func ExecuteFill(orderId int64) error {
 					bills = append(bills, bill)
 				}
 			} else {
+				// What was held and not sold, which for a market sell by funds
+				// is whatever the funds were received without
 				remainingSize := order.Size.Sub(order.FilledSize)
 				if remainingSize.GreaterThan(decimal.Zero) {
 					bill, err := AddDelayBill(db, int64(order.UserId), product.BaseCurrency, remainingSize, remainingSize.Neg(), entities.BillTypeTrade, notes)
@@ -192,6 +686,13 @@ func ExecuteFill(orderId int64) error {
 		return err
 	}
 
+	if group != nil {
+		err = db.UpdateOrderGroup(group)
+		if err != nil {
+			return err
+		}
+	}
+
 	for _, fill := range fills {
 		err = db.UpdateFill(fill)
 		if err != nil {
@@ -201,6 +702,170 @@ func ExecuteFill(orderId int64) error {
 	return db.CommitTx()
 }
 
+// releaseAmendHold gives back what was held for the amend in flight of the
+// order, which won't be applied
+func releaseAmendHold(db store.Store, order *entities.Order, product *entities.Product, notes string) (*entities.Bill, error) {
+	hold := order.AmendHold
+	holdCurrency := product.BaseCurrency
+	if order.Side == entities.SideBuy {
+		holdCurrency = product.QuoteCurrency
+		order.Funds = order.Funds.Sub(hold)
+	} else {
+		order.Size = order.Size.Sub(hold)
+	}
+	order.AmendPending = false
+	order.AmendHold = decimal.Zero
+
+	if hold.IsZero() {
+		return nil, nil
+	}
+	return AddDelayBill(db, int64(order.UserId), holdCurrency, hold, hold.Neg(), entities.BillTypeTrade, notes)
+}
+
+// CancelAmend rolls back an amend which never reached the matching engine,
+// releasing what was held for it
+func CancelAmend(orderId int64) error {
+	db, err := mysql.SharedStore().BeginTx()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Rollback() }()
+
+	order, err := db.GetOrderByIdForUpdate(orderId)
+	if err != nil {
+		return err
+	}
+	if order == nil {
+		return fmt.Errorf("order not found: %v", orderId)
+	}
+	if !order.AmendPending {
+		return nil
+	}
+
+	product, err := GetProductById(strconv.Itoa(order.ProductId))
+	if err != nil {
+		return err
+	}
+	if product == nil {
+		return fmt.Errorf("product not found: %v", order.ProductId)
+	}
+
+	_, err = releaseAmendHold(db, order, product, fmt.Sprintf("%v-amend", order.ID))
+	if err != nil {
+		return err
+	}
+
+	err = db.UpdateOrder(order)
+	if err != nil {
+		return err
+	}
+	return db.CommitTx()
+}
+
+// RejectUnsentOrders rejects the orders which never reached the matching
+// engine and releases what was held for them, in a single transaction
+func RejectUnsentOrders(orders []*entities.Order) error {
+	db, err := mysql.SharedStore().BeginTx()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Rollback() }()
+
+	products := map[int]*entities.Product{}
+	groups := map[int64]bool{}
+	for _, unsent := range orders {
+		order, err := db.GetOrderByIdForUpdate(int64(unsent.ID))
+		if err != nil {
+			return err
+		}
+		if order == nil {
+			return fmt.Errorf("order not found: %v", unsent.ID)
+		}
+		if order.Status != entities.OrderStatusNew {
+			continue
+		}
+
+		product, found := products[order.ProductId]
+		if !found {
+			product, err = GetProductById(strconv.Itoa(order.ProductId))
+			if err != nil {
+				return err
+			}
+			if product == nil {
+				return fmt.Errorf("product not found: %v", order.ProductId)
+			}
+			products[order.ProductId] = product
+		}
+
+		order.Status = entities.OrderStatusRejected
+		order.RejectReason = entities.RejectReasonNotSent
+
+		// Legs of a group hold once for the group, which is released with
+		// its first leg
+		holdCurrency, hold := product.BaseCurrency, order.Size
+		if order.GroupId != 0 && order.GroupRole != entities.OrderGroupRoleEntry {
+			if groups[order.GroupId] {
+				hold = decimal.Zero
+			} else {
+				groups[order.GroupId] = true
+				group, err := db.GetOrderGroupByIdForUpdate(order.GroupId)
+				if err != nil {
+					return err
+				}
+				if group == nil {
+					return fmt.Errorf("order group not found: %v", order.GroupId)
+				}
+				holdCurrency, hold = group.HoldCurrency, group.Hold
+				group.Hold = decimal.Zero
+				group.OpenLegs = 0
+				err = db.UpdateOrderGroup(group)
+				if err != nil {
+					return err
+				}
+			}
+		} else if order.Side == entities.SideBuy {
+			holdCurrency, hold = product.QuoteCurrency, order.Funds
+		}
+		if hold.GreaterThan(decimal.Zero) {
+			_, err = AddDelayBill(db, int64(order.UserId), holdCurrency, hold, hold.Neg(), entities.BillTypeTrade,
+				fmt.Sprintf("%v-unsent", order.ID))
+			if err != nil {
+				return err
+			}
+		}
+
+		err = db.UpdateOrder(order)
+		if err != nil {
+			return err
+		}
+		unsent.Status, unsent.RejectReason = order.Status, order.RejectReason
+	}
+	return db.CommitTx()
+}
+
+// settleLateRejections marks the unsettled rejection fills of a done order as
+// settled, without any bill
+func settleLateRejections(db store.Store, orderId int64) (bool, error) {
+	fills, err := db.GetUnsettledFillsByOrderId(orderId)
+	if err != nil {
+		return false, err
+	}
+
+	var settled bool
+	for _, fill := range fills {
+		if len(fill.RejectReason) == 0 {
+			continue
+		}
+		fill.Settled = true
+		err = db.UpdateFill(fill)
+		if err != nil {
+			return false, err
+		}
+		settled = true
+	}
+	return settled, nil
+}
+
 func GetOrderById(orderId int64) (*entities.Order, error) {
 	return mysql.SharedStore().GetOrderById(orderId)
 }
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/irononet/go-exchange/entities"
+	"github.com/shopspring/decimal"
+)
+
+type ProductRule string
+
+const (
+	ProductRulePrice       = ProductRule("PRICE")
+	ProductRuleTickSize    = ProductRule("TICK_SIZE")
+	ProductRuleLotSize     = ProductRule("LOT_SIZE")
+	ProductRuleMinSize     = ProductRule("MIN_SIZE")
+	ProductRuleMaxSize     = ProductRule("MAX_SIZE")
+	ProductRuleMinNotional = ProductRule("MIN_NOTIONAL")
+	ProductRuleMaxNotional = ProductRule("MAX_NOTIONAL")
+	ProductRulePriceBand   = ProductRule("PRICE_BAND")
+)
+
+// ProductRuleError tells which rule of the product a field of the order breaks,
+// and the limit the field has to keep to
+type ProductRuleError struct {
+	Rule  ProductRule
+	Field string
+	Value decimal.Decimal
+	Limit decimal.Decimal
+}
+
+func (e *ProductRuleError) Error() string {
+	switch e.Rule {
+	case ProductRulePrice:
+		return fmt.Sprintf("%v %v must be greater than 0", e.Field, e.Value)
+	case ProductRuleTickSize, ProductRuleLotSize:
+		return fmt.Sprintf("%v %v is not a multiple of %v", e.Field, e.Value, e.Limit)
+	case ProductRuleMinSize, ProductRuleMinNotional:
+		return fmt.Sprintf("%v %v less than %v", e.Field, e.Value, e.Limit)
+	case ProductRulePriceBand:
+		if e.Value.LessThan(e.Limit) {
+			return fmt.Sprintf("%v %v below the price band, less than %v", e.Field, e.Value, e.Limit)
+		}
+		return fmt.Sprintf("%v %v above the price band, greater than %v", e.Field, e.Value, e.Limit)
+	default:
+		return fmt.Sprintf("%v %v greater than %v", e.Field, e.Value, e.Limit)
+	}
+}
+
+// ProductRules checks the orders of a product against its tick size, lot size
+// and notional limits. A zero max means no limit.
+type ProductRules struct {
+	product *entities.Product
+}
+
+func NewProductRules(product *entities.Product) *ProductRules {
+	return &ProductRules{product: product}
+}
+
+// TickSize is the increment of prices
+func (r *ProductRules) TickSize() decimal.Decimal {
+	return r.product.TickSize()
+}
+
+// LotSize is the increment of sizes
+func (r *ProductRules) LotSize() decimal.Decimal {
+	return decimal.New(1, -r.product.BaseScale)
+}
+
+func (r *ProductRules) ValidatePrice(field string, price decimal.Decimal) error {
+	if price.LessThanOrEqual(decimal.Zero) {
+		return &ProductRuleError{ProductRulePrice, field, price, decimal.Zero}
+	}
+	if !price.Mod(r.TickSize()).IsZero() {
+		return &ProductRuleError{ProductRuleTickSize, field, price, r.TickSize()}
+	}
+	return nil
+}
+
+func (r *ProductRules) ValidateSize(field string, size decimal.Decimal) error {
+	if !size.Mod(r.LotSize()).IsZero() {
+		return &ProductRuleError{ProductRuleLotSize, field, size, r.LotSize()}
+	}
+	if size.LessThan(r.product.BaseMinSize) {
+		return &ProductRuleError{ProductRuleMinSize, field, size, r.product.BaseMinSize}
+	}
+	if r.product.BaseMaxSize.GreaterThan(decimal.Zero) && size.GreaterThan(r.product.BaseMaxSize) {
+		return &ProductRuleError{ProductRuleMaxSize, field, size, r.product.BaseMaxSize}
+	}
+	return nil
+}
+
+func (r *ProductRules) ValidateNotional(field string, notional decimal.Decimal) error {
+	quoteLotSize := decimal.New(1, -r.product.QuoteScale)
+	if !notional.Mod(quoteLotSize).IsZero() {
+		return &ProductRuleError{ProductRuleLotSize, field, notional, quoteLotSize}
+	}
+	if notional.LessThan(r.product.QuoteMinSize) {
+		return &ProductRuleError{ProductRuleMinNotional, field, notional, r.product.QuoteMinSize}
+	}
+	if r.product.QuoteMaxSize.GreaterThan(decimal.Zero) && notional.GreaterThan(r.product.QuoteMaxSize) {
+		return &ProductRuleError{ProductRuleMaxNotional, field, notional, r.product.QuoteMaxSize}
+	}
+	return nil
+}
+
+// ValidateLimit checks the size and price of a limit order and what they are worth
+func (r *ProductRules) ValidateLimit(size, price decimal.Decimal) error {
+	if err := r.ValidatePrice("price", price); err != nil {
+		return err
+	}
+	if err := r.ValidateSize("size", size); err != nil {
+		return err
+	}
+
+	// the notional of a limit order is not bound to the quote scale
+	notional := size.Mul(price)
+	if notional.LessThan(r.product.QuoteMinSize) {
+		return &ProductRuleError{ProductRuleMinNotional, "notional", notional, r.product.QuoteMinSize}
+	}
+	if r.product.QuoteMaxSize.GreaterThan(decimal.Zero) && notional.GreaterThan(r.product.QuoteMaxSize) {
+		return &ProductRuleError{ProductRuleMaxNotional, "notional", notional, r.product.QuoteMaxSize}
+	}
+	return nil
+}
+
+// ValidatePriceBand checks that a limit price is not further away from the
+// reference price than the price band of the product allows
+func (r *ProductRules) ValidatePriceBand(field string, price, referencePrice decimal.Decimal) error {
+	low, high, ok := r.product.PriceLimits.PriceBand(referencePrice)
+	if !ok {
+		return nil
+	}
+	if price.LessThan(low) {
+		return &ProductRuleError{ProductRulePriceBand, field, price, low}
+	}
+	if price.GreaterThan(high) {
+		return &ProductRuleError{ProductRulePriceBand, field, price, high}
+	}
+	return nil
+}
@@ -1,8 +1,14 @@
 package service
 
 import (
+	"fmt"
+	"strconv"
+	"time"
+
 	"github.com/irononet/go-exchange/entities"
 	"github.com/irononet/go-exchange/store/mysql"
+	"github.com/shopspring/decimal"
+	"github.com/siddontang/go-log/log"
 )
 
 func GetProductById(id string) (*entities.Product, error) {
@@ -12,3 +18,185 @@ func GetProductById(id string) (*entities.Product, error) {
 func GetProducts() ([]*entities.Product, error) {
 	return mysql.SharedStore().GetProducts()
 }
+
+// AddProduct lists a product. A new listing may open with an auction, the first
+// price is then set by the uncross instead of the first aggressive order.
+func AddProduct(product *entities.Product) error {
+	if err := validateProduct(product); err != nil {
+		return err
+	}
+	if len(product.TradingStatus) > 0 {
+		if _, err := entities.NewTradingStatusFromString(string(product.TradingStatus)); err != nil {
+			return err
+		}
+	}
+	product.Status = entities.ProductStatusOnline
+	return mysql.SharedStore().AddProduct(product)
+}
+
+// UpdateProduct changes the rules of a product, its status is changed with
+// SetProductStatus
+func UpdateProduct(product *entities.Product) error {
+	if err := validateProduct(product); err != nil {
+		return err
+	}
+	return mysql.SharedStore().UpdateProduct(product)
+}
+
+// CheckProduct validates the settings of a product before its matching engine
+// is sent them, they are recorded with UpdateProduct once it has been
+func CheckProduct(product *entities.Product) error {
+	return validateProduct(product)
+}
+
+func validateProduct(product *entities.Product) error {
+	if len(product.BaseCurrency) == 0 || len(product.QuoteCurrency) == 0 {
+		return fmt.Errorf("base and quote currency are required")
+	}
+	if product.BaseScale < 0 || product.QuoteScale < 0 {
+		return fmt.Errorf("scale less than 0")
+	}
+	if product.BaseMinSize.LessThanOrEqual(decimal.Zero) {
+		return fmt.Errorf("base min size %v less than or equal to 0", product.BaseMinSize)
+	}
+	if product.BaseMaxSize.GreaterThan(decimal.Zero) && product.BaseMaxSize.LessThan(product.BaseMinSize) {
+		return fmt.Errorf("base max size %v less than base min size %v", product.BaseMaxSize, product.BaseMinSize)
+	}
+	if product.QuoteMaxSize.GreaterThan(decimal.Zero) && product.QuoteMaxSize.LessThan(product.QuoteMinSize) {
+		return fmt.Errorf("quote max size %v less than quote min size %v", product.QuoteMaxSize, product.QuoteMinSize)
+	}
+	if product.QuoteIncrement < 0 {
+		return fmt.Errorf("quote increment %v less than 0", product.QuoteIncrement)
+	}
+
+	if len(product.MatchingAlgorithm) > 0 {
+		if _, err := entities.NewMatchingAlgorithmFromString(string(product.MatchingAlgorithm)); err != nil {
+			return err
+		}
+	}
+
+	limits := product.PriceLimits
+	if limits.PriceBandPercent.LessThan(decimal.Zero) {
+		return fmt.Errorf("price band percent %v less than 0", limits.PriceBandPercent)
+	}
+	if limits.CircuitBreakerPercent.LessThan(decimal.Zero) {
+		return fmt.Errorf("circuit breaker percent %v less than 0", limits.CircuitBreakerPercent)
+	}
+	if limits.CircuitBreakerPercent.GreaterThan(decimal.Zero) &&
+		(limits.CircuitBreakerWindowSeconds <= 0 || limits.CircuitBreakerHaltSeconds <= 0) {
+		return fmt.Errorf("circuit breaker window and halt seconds must be greater than 0")
+	}
+	return nil
+}
+
+// SetProductStatus pauses, resumes or delists a product. A delisted product
+// can't come back, and a product is only delisted once none of its orders is
+// left on the book.
+func SetProductStatus(productId string, status entities.ProductStatus) (*entities.Product, error) {
+	product, err := GetProductById(productId)
+	if err != nil {
+		return nil, err
+	}
+	if product == nil {
+		return nil, fmt.Errorf("product not found: %v", productId)
+	}
+	if product.Status == entities.ProductStatusDelisted {
+		return nil, fmt.Errorf("product %v is delisted", productId)
+	}
+
+	if status == entities.ProductStatusDelisted {
+		orders, err := GetActiveOrdersByProductId(productId, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(orders) > 0 {
+			return nil, fmt.Errorf("product %v still has active orders", productId)
+		}
+	}
+
+	product.Status = status
+	return product, mysql.SharedStore().UpdateProduct(product)
+}
+
+// CheckTradingStatus returns the product with the trading status applied, to be
+// recorded with RecordTradingStatus once the matching engine of the product, which
+// applies it in order with the other commands, has been sent the status. An
+// auction may end by itself at auctionEnd, the product then trades continuously
+// again.
+func CheckTradingStatus(productId string, status entities.TradingStatus, auctionEnd *time.Time) (*entities.Product, error) {
+	if _, err := entities.NewTradingStatusFromString(string(status)); err != nil {
+		return nil, err
+	}
+	if auctionEnd != nil {
+		if status != entities.TradingStatusAuction {
+			return nil, fmt.Errorf("auction end is only allowed for the %v status", entities.TradingStatusAuction)
+		}
+		if !auctionEnd.After(time.Now()) {
+			return nil, fmt.Errorf("auction end %v is not in the future", auctionEnd)
+		}
+	}
+
+	product, err := GetProductById(productId)
+	if err != nil {
+		return nil, err
+	}
+	if product == nil {
+		return nil, fmt.Errorf("product not found: %v", productId)
+	}
+
+	product.TradingStatus = status
+	return product, nil
+}
+
+// RecordTradingStatus records the trading status the matching engine of the
+// product switched to, whether it was sent the status or switched by itself at
+// the end of an auction or a halt of the circuit breaker
+func RecordTradingStatus(productId int64, status entities.TradingStatus) error {
+	return mysql.SharedStore().UpdateProductTradingStatus(productId, status)
+}
+
+// GetActiveOrdersByProductId returns the orders the engine of the product may
+// still hold
+func GetActiveOrdersByProductId(productId string, limit int) ([]*entities.Order, error) {
+	return mysql.SharedStore().GetOrdersByProductId(productId, []entities.OrderStatus{entities.OrderStatusNew,
+		entities.OrderStatusOpen, entities.OrderStatusCancelling}, limit)
+}
+
+// WatchProducts calls start for every product which is not delisted and stop
+// once it is, first for the products already listed and then as they change,
+// checking every interval. Paused products keep running so their orders can
+// still be cancelled.
+func WatchProducts(interval time.Duration, start func(product *entities.Product), stop func(product *entities.Product)) {
+	running := map[string]bool{}
+
+	refresh := func() {
+		products, err := GetProducts()
+		if err != nil {
+			log.Error(err)
+			return
+		}
+
+		for _, product := range products {
+			productId := strconv.Itoa(int(product.ID))
+			if product.Status == entities.ProductStatusDelisted {
+				if running[productId] {
+					stop(product)
+					delete(running, productId)
+				}
+			} else if !running[productId] {
+				start(product)
+				running[productId] = true
+			}
+		}
+	}
+
+	refresh()
+	go func() {
+		for {
+			select {
+			case <-time.After(interval):
+				refresh()
+			}
+		}
+	}()
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/irononet/go-exchange/entities"
+	"github.com/irononet/go-exchange/store/mysql"
+	"github.com/shopspring/decimal"
+)
+
+// maxQuoteLevels caps the number of quotes of a mass quote
+const maxQuoteLevels = 100
+
+// QuoteLevel is a quote of a mass quote, a limit order at a price level
+type QuoteLevel struct {
+	Side  entities.Side
+	Price decimal.Decimal
+	Size  decimal.Decimal
+}
+
+// PlaceMassQuote validates the quotes of a market maker and adds them in a single
+// transaction, holding once per currency. The matching engine cancels the quotes
+// the user has on the book and places these ones in the same step. What the
+// cancelled quotes hold is released once they are settled, until then the new
+// quotes are held on top of it.
+func PlaceMassQuote(userId int64, productId string, levels []QuoteLevel, postOnly bool) ([]*entities.Order, error) {
+	if len(levels) > maxQuoteLevels {
+		return nil, fmt.Errorf("%v quotes, at most %v are allowed", len(levels), maxQuoteLevels)
+	}
+	if err := validateQuoteLevels(levels); err != nil {
+		return nil, err
+	}
+
+	product, err := getProductAcceptingOrders(productId)
+	if err != nil {
+		return nil, err
+	}
+
+	holds := map[string]decimal.Decimal{}
+	var orders []*entities.Order
+	for _, level := range levels {
+		if _, err := entities.NewSideFromString(string(level.Side)); err != nil {
+			return nil, err
+		}
+
+		order, holdCurrency, holdSize, err := newOrder(product, userId, "", entities.LIMIT_ORDER, level.Side,
+			level.Size, level.Price, decimal.Zero, OrderOptions{PostOnly: postOnly})
+		if err != nil {
+			return nil, err
+		}
+		order.Quote = true
+		orders = append(orders, order)
+
+		holds[holdCurrency] = holds[holdCurrency].Add(holdSize)
+	}
+
+	db, err := mysql.SharedStore().BeginTx()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = db.Rollback() }()
+
+	for _, currency := range []string{product.BaseCurrency, product.QuoteCurrency} {
+		if holds[currency].IsZero() {
+			continue
+		}
+		err = HoldBalance(db, userId, currency, holds[currency], entities.BillTypeTrade)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = db.AddOrders(orders)
+	if err != nil {
+		return nil, err
+	}
+	return orders, db.CommitTx()
+}
+
+// validateQuoteLevels checks that none of the quotes of a side crosses a quote
+// of the other side, which would make the market maker trade with itself
+func validateQuoteLevels(levels []QuoteLevel) error {
+	var bestBid, bestAsk decimal.Decimal
+	for _, level := range levels {
+		if level.Side == entities.SideBuy && level.Price.GreaterThan(bestBid) {
+			bestBid = level.Price
+		}
+		if level.Side == entities.SideSell && (bestAsk.IsZero() || level.Price.LessThan(bestAsk)) {
+			bestAsk = level.Price
+		}
+	}
+	if !bestAsk.IsZero() && bestBid.GreaterThanOrEqual(bestAsk) {
+		return errors.New("quotes cross each other")
+	}
+	return nil
+}
@@ -5,6 +5,10 @@ import (
 	"github.com/irononet/go-exchange/store/mysql"
 )
 
+func GetLastTradeByProductId(productId string) (*entities.Trade, error) {
+	return mysql.SharedStore().GetLastTradeByProduct(productId)
+}
+
 func GetTradesByProductId(productId string, count int) ([]*entities.Trade, error) {
 	return mysql.SharedStore().GetTradesByProductId(productId, count)
 }
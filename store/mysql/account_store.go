@@ -27,7 +27,7 @@ func (s *Store) GetAccountsByUserId(userId int64) ([]*entities.Account, error) {
 
 func (s *Store) GetAccountForUpdate(userId int64, currency string) (*entities.Account, error) {
 	var account entities.Account
-	err := s.db.Where("user_id = ?", userId).Where("currency = ?", currency).Find(&account).Error
+	err := s.db.Raw("SELECT * FROM accounts WHERE user_id=? AND currency=? FOR UPDATE", userId, currency).Scan(&account).Error
 	if err == gorm.ErrRecordNotFound {
 		return nil, nil
 	}
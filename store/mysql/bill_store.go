@@ -7,7 +7,7 @@ import (
 
 func (s *Store) GetUnsettledBillsByUserId(userId int64, currency string) ([]*entities.Bill, error) {
 	var bills []*entities.Bill
-	db := s.db.Where("user_id = ?", userId).Where("currency = ?", currency).Order("id ASC").Limit(100)
+	db := s.db.Where("user_id = ?", userId).Where("currency = ?", currency).Where("settled = ?", 0).Order("id ASC").Limit(100)
 	err := db.Find(&bills).Error
 	return bills, err
 }
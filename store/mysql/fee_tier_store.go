@@ -0,0 +1,14 @@
+package mysql
+
+import "github.com/irononet/go-exchange/entities"
+
+func (s *Store) GetFeeTiersByProductId(productId string) ([]*entities.FeeTier, error) {
+	var tiers []*entities.FeeTier
+	err := s.db.Where("product_id = ?", productId).Order("min_volume ASC").Find(&tiers).Error
+	if err != nil || len(tiers) != 0 {
+		return tiers, err
+	}
+
+	err = s.db.Where("product_id = ?", 0).Order("min_volume ASC").Find(&tiers).Error
+	return tiers, err
+}
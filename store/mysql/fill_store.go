@@ -1,7 +1,10 @@
 package mysql
 
 import (
+	"time"
+
 	"github.com/irononet/go-exchange/entities"
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
 
@@ -30,6 +33,51 @@ func (s *Store) GetUnsettledFillsByOrderId(orderId int64) ([]*entities.Fill, err
 	return fills, err
 }
 
+func (s *Store) GetFillsByUserId(userId int64, productId string, orderId int64, beforeId, afterId int64, limit int) ([]*entities.Fill, error) {
+	db := s.db.Joins("JOIN orders ON orders.id = fills.order_id").
+		Where("orders.user_id = ?", userId).
+		Where("fills.done = ?", 0).Where("fills.amend = ?", 0)
+
+	if len(productId) != 0 {
+		db = db.Where("fills.product_id = ?", productId)
+	}
+	if orderId > 0 {
+		db = db.Where("fills.order_id = ?", orderId)
+	}
+	if beforeId > 0 {
+		db = db.Where("fills.id > ?", beforeId)
+	}
+	if afterId > 0 {
+		db = db.Where("fills.id < ?", afterId)
+	}
+
+	if limit <= 0 {
+		limit = 100
+	}
+
+	var fills []*entities.Fill
+	err := db.Order("fills.id DESC").Limit(limit).Find(&fills).Error
+	return fills, err
+}
+
+// GetUserVolume sums the quote volume the user traded on the product between the
+// times
+func (s *Store) GetUserVolume(userId int64, productId string, since, until time.Time) (decimal.Decimal, error) {
+	var volume decimal.NullDecimal
+	err := s.db.Table("fills").Select("SUM(fills.size * fills.price)").
+		Joins("JOIN orders ON orders.id = fills.order_id").
+		Where("orders.user_id = ?", userId).
+		Where("fills.product_id = ?", productId).
+		Where("fills.done = ?", 0).Where("fills.amend = ?", 0).
+		Where("fills.created_at >= ?", since).
+		Where("fills.created_at < ?", until).
+		Row().Scan(&volume)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return volume.Decimal, nil
+}
+
 func (s *Store) UpdateFill(fill *entities.Fill) error {
 	return s.db.Save(fill).Error
 }
@@ -0,0 +1,27 @@
+package mysql
+
+import (
+	"time"
+
+	"github.com/irononet/go-exchange/entities"
+	"gorm.io/gorm"
+)
+
+func (s *Store) GetOrderGroupByIdForUpdate(groupId int64) (*entities.OrderGroup, error) {
+	var group entities.OrderGroup
+	err := s.db.Raw("SELECT * FROM order_groups WHERE id=? FOR UPDATE", groupId).Scan(&group).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &group, err
+}
+
+func (s *Store) AddOrderGroup(group *entities.OrderGroup) error {
+	group.CreatedAt = time.Now()
+	return s.db.Create(group).Error
+}
+
+func (s *Store) UpdateOrderGroup(group *entities.OrderGroup) error {
+	group.UpdatedAt = time.Now()
+	return s.db.Save(group).Error
+}
@@ -65,11 +65,32 @@ func (s *Store) GetOrderByUserId(userId int64, statuses []entities.OrderStatus,
 	return orders, err
 }
 
+func (s *Store) GetOrdersByProductId(productId string, statuses []entities.OrderStatus, limit int) ([]*entities.Order, error) {
+	db := s.db.Where("product_id=?", productId)
+	if len(statuses) != 0 {
+		db = db.Where("status IN (?)", statuses)
+	}
+
+	var orders []*entities.Order
+	err := db.Order("id ASC").Limit(limit).Find(&orders).Error
+	return orders, err
+}
+
 func (s *Store) AddOrder(order *entities.Order) error {
 	order.CreatedAt = time.Now()
 	return s.db.Create(order).Error
 }
 
+func (s *Store) AddOrders(orders []*entities.Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+	for _, order := range orders {
+		order.CreatedAt = time.Now()
+	}
+	return s.db.Create(&orders).Error
+}
+
 func (s *Store) UpdateOrder(order *entities.Order) error {
 	order.UpdatedAt = time.Now()
 	return s.db.Save(order).Error
@@ -8,12 +8,24 @@ import (
 func (s *Store) GetProductById(id string) (*entities.Product, error) {
 	var product entities.Product
 	err := s.db.Where("id=?", id).Find(&product).Error
-	if err == gorm.ErrRecordNotFound {
+	if err == gorm.ErrRecordNotFound || (err == nil && product.ID == 0) {
 		return nil, nil
 	}
 	return &product, err
 }
 
+func (s *Store) AddProduct(product *entities.Product) error {
+	return s.db.Create(product).Error
+}
+
+func (s *Store) UpdateProduct(product *entities.Product) error {
+	return s.db.Save(product).Error
+}
+
+func (s *Store) UpdateProductTradingStatus(productId int64, status entities.TradingStatus) error {
+	return s.db.Model(&entities.Product{}).Where("id=?", productId).Update("trading_status", status).Error
+}
+
 func (s *Store) GetProducts() ([]*entities.Product, error) {
 	var products []*entities.Product
 	err := s.db.Find(&products).Error
@@ -50,6 +50,7 @@ func initDb() error {
 		var tables = []interface{}{
 			&entities.Account{},
 			&entities.Order{},
+			&entities.OrderGroup{},
 			&entities.Product{},
 			&entities.Trade{},
 			&entities.Fill{},
@@ -57,6 +58,7 @@ func initDb() error {
 			&entities.Bill{},
 			&entities.Tick{},
 			&entities.Config{},
+			&entities.FeeTier{},
 		}
 
 		for _, table := range tables {
@@ -8,7 +8,7 @@ import (
 func (s *Store) GetLastTradeByProduct(productId string) (*entities.Trade, error) {
 	var trade entities.Trade
 	err := s.db.Where("product_id=?", productId).Order("id DESC").Limit(1).Find(&trade).Error
-	if err == gorm.ErrRecordNotFound {
+	if err == gorm.ErrRecordNotFound || (err == nil && trade.ID == 0) {
 		return nil, nil
 	}
 	return &trade, err
@@ -1,6 +1,11 @@
 package store
 
-import "github.com/irononet/go-exchange/entities"
+import (
+	"time"
+
+	"github.com/irononet/go-exchange/entities"
+	"github.com/shopspring/decimal"
+)
 
 type Store interface {
 	BeginTx() (Store, error)
@@ -23,8 +28,13 @@ type Store interface {
 	// Config store methods
 	GetConfigs() ([]*entities.Config, error)
 
+	// Fee tier store methods
+	GetFeeTiersByProductId(productId string) ([]*entities.FeeTier, error)
+
 	// Fill store methods
 	GetLastFillByProductId(productId string) (*entities.Fill, error)
+	GetFillsByUserId(userId int64, productId string, orderId int64, beforeId, afterId int64, limit int) ([]*entities.Fill, error)
+	GetUserVolume(userId int64, productId string, since, until time.Time) (decimal.Decimal, error)
 	GetUnsettledFillsByOrderId(orderId int64) ([]*entities.Fill, error)
 	GetUnsettledFills(count int) ([]*entities.Fill, error)
 	UpdateFill(fill *entities.Fill) error
@@ -35,13 +45,23 @@ type Store interface {
 	GetOrderByClientUid(orderId int64, clientUid string) (*entities.Order, error)
 	GetOrderByIdForUpdate(orderId int64) (*entities.Order, error)
 	GetOrderByUserId(userId int64, statuses []entities.OrderStatus, side *entities.Side, productId string, beforeId, afterId int64, limit int) ([]*entities.Order, error)
+	GetOrdersByProductId(productId string, statuses []entities.OrderStatus, limit int) ([]*entities.Order, error)
 	AddOrder(order *entities.Order) error
+	AddOrders(orders []*entities.Order) error
 	UpdateOrder(order *entities.Order) error
 	UpdateOrderStatus(orderId int64, oldStatus, newStatus entities.OrderStatus) (bool, error)
 
+	// Order group store methods
+	GetOrderGroupByIdForUpdate(groupId int64) (*entities.OrderGroup, error)
+	AddOrderGroup(group *entities.OrderGroup) error
+	UpdateOrderGroup(group *entities.OrderGroup) error
+
 	// Product store methods
 	GetProductById(id string) (*entities.Product, error)
 	GetProducts() ([]*entities.Product, error)
+	AddProduct(product *entities.Product) error
+	UpdateProduct(product *entities.Product) error
+	UpdateProductTradingStatus(productId int64, status entities.TradingStatus) error
 
 	// Tick store methods
 	GetTicksByProductId(productId string, granularity int64, limit int) ([]*entities.Tick, error)
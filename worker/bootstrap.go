@@ -0,0 +1,55 @@
+package worker
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/irononet/go-exchange/conf"
+	"github.com/irononet/go-exchange/entities"
+	"github.com/irononet/go-exchange/matching"
+	"github.com/irononet/go-exchange/service"
+	"github.com/siddontang/go-log/log"
+)
+
+// how often the products are checked for listings and delistings
+const productWatchInterval = 10 * time.Second
+
+// productMakers turn the logs of a product into fills, trades and ticks
+type productMakers struct {
+	fillMaker  *FillMaker
+	tradeMaker *TradeMaker
+	tickMaker  *TickMaker
+}
+
+func StartMakers() {
+	gexConfig := conf.GetConfig()
+
+	makers := map[string]*productMakers{}
+
+	service.WatchProducts(productWatchInterval, func(product *entities.Product) {
+		productId := strconv.Itoa(int(product.ID))
+		m := &productMakers{
+			fillMaker:  NewFillMaker(matching.NewKafkaLogReader("fillMaker", productId, gexConfig.Kafka.Brokers)),
+			tradeMaker: NewTradeMaker(matching.NewKafkaLogReader("tradeMaker", productId, gexConfig.Kafka.Brokers)),
+			tickMaker:  NewTickMaker(productId, matching.NewKafkaLogReader("tickMaker", productId, gexConfig.Kafka.Brokers)),
+		}
+		m.fillMaker.Start()
+		m.tradeMaker.Start()
+		m.tickMaker.Start()
+		makers[productId] = m
+
+		log.Infof("makers of %v started", productId)
+	}, func(product *entities.Product) {
+		productId := strconv.Itoa(int(product.ID))
+		if m, found := makers[productId]; found {
+			m.fillMaker.Stop()
+			m.tradeMaker.Stop()
+			m.tickMaker.Stop()
+			delete(makers, productId)
+		}
+
+		log.Infof("makers of %v stopped", productId)
+	})
+
+	log.Info("makers ok")
+}
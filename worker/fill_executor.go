@@ -33,6 +33,16 @@ func NewFillExecutor() *FillExecutor{
 			for{
 				select{
 				case fill := <- f.WorkerChs[idx]: 
+					// A rejection may come after the order is done, it's
+					// settled all the same
+					if len(fill.RejectReason) > 0{
+						err := service.ExecuteFill(fill.OrderId)
+						if err != nil{
+							log.Error(err)
+						}
+						continue
+					}
+
 					if settleOrderCache.Contains(fill.OrderId){
 						continue 
 					}
@@ -45,7 +55,8 @@ func NewFillExecutor() *FillExecutor{
 						log.Warnf("order not found: %v", fill.OrderId)
 						continue 
 					}
-					if order.Status == entities.OrderStatusCancelled || order.Status == entities.OrderStatusFilled{
+					if order.Status == entities.OrderStatusCancelled || order.Status == entities.OrderStatusFilled ||
+						order.Status == entities.OrderStatusRejected{
 						settleOrderCache.Add(order.ID, struct{}{}) 
 						continue
 					}
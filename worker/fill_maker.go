@@ -45,6 +45,13 @@ func (t *FillMaker) Start(){
 	go t.flusher()
 }
 
+// Stop stops reading logs, what is buffered is still flushed
+func (t *FillMaker) Stop(){
+	t.LogReader.Stop()
+}
+
+// OnMatchLog makes a fill for each order of the trade. The side of the match
+// log is the one of the maker, the taker is on the other side
 func (t *FillMaker) OnMatchLog(log *matching.MatchLog, offset int64){
 	t.FillCh <- &entities.Fill{
 		TradeId: log.TradeId, 
@@ -54,7 +61,7 @@ func (t *FillMaker) OnMatchLog(log *matching.MatchLog, offset int64){
 		Size: log.Size, 
 		Price: log.Price, 
 		Liquidity: "T", 
-		Side: log.Side, 
+		Side: log.Side.Opposite(), 
 		LogOffset: offset, 
 		LogSeq: log.Sequence, 
 	}
@@ -67,12 +74,16 @@ func (t *FillMaker) OnMatchLog(log *matching.MatchLog, offset int64){
 		Size: log.Size, 
 		Price: log.Price, 
 		Liquidity: "M", 
-		Side: log.Side.Opposite(), 
+		Side: log.Side, 
 		LogOffset: offset, 
 		LogSeq: log.Sequence,
 	}
 }
 
+func (t *FillMaker) OnReceivedLog(log *matching.ReceivedLog, offset int64){
+	// do nothing, an order is settled by the logs of what happens to it
+}
+
 func (t *FillMaker) OnOpenLOg(log *matching.OpenLog, offset int64){
 	_, _ = service.UpdateOrderStatus(log.OrderId, entities.OrderStatusNew, entities.OrderStatusOpen)
 }
@@ -90,6 +101,53 @@ func (t *FillMaker) OnDoneLog(log *matching.DoneLog, offset int64){
 	}
 }
 
+func (t *FillMaker) OnActivateLog(log *matching.ActivateLog, offset int64){
+	// do nothing, an activated stop order is settled by the match and done
+	// logs that follow
+}
+
+func (t *FillMaker) OnChangeLog(log *matching.ChangeLog, offset int64){
+	// do nothing, the hold of the decremented size is released with the
+	// remaining of the order once it's done
+}
+
+func (t *FillMaker) OnAmendLog(log *matching.AmendLog, offset int64){
+	t.FillCh <- &entities.Fill{
+		MessageSeq: log.Sequence, 
+		OrderId: log.OrderId, 
+		ProductId: log.ProductId, 
+		Size: log.Size, 
+		Price: log.Price, 
+		Side: log.Side, 
+		Amend: true, 
+		LogOffset: offset, 
+		LogSeq: log.Sequence,
+	}
+}
+
+func (t *FillMaker) OnStatusLog(statusLog *matching.StatusLog, offset int64){
+	// the orders turned away by the status come with done logs, the status
+	// itself is recorded for the product
+	err := service.RecordTradingStatus(statusLog.ProductId, statusLog.Status) 
+	if err != nil{
+		log.Error(err)
+	}
+}
+
+func (t *FillMaker) OnRejectedLog(log *matching.RejectedLog, offset int64){
+	t.FillCh <- &entities.Fill{
+		MessageSeq: log.Sequence, 
+		OrderId: log.OrderId, 
+		ProductId: log.ProductId, 
+		Side: log.Side, 
+		Done: !log.Amend, 
+		Amend: log.Amend, 
+		RejectReason: log.Reason, 
+		LogOffset: offset, 
+		LogSeq: log.Sequence,
+	}
+}
+
 func (t *FillMaker) flusher(){
 	var fills []*entities.Fill 
 
@@ -52,6 +52,15 @@ func (t *TickMaker) Start(){
 	go t.flusher() 
 }
 
+// Stop stops reading logs, what is buffered is still flushed
+func (t *TickMaker) Stop(){
+	t.LogReader.Stop()
+}
+
+func (t *TickMaker) OnReceivedLog(log *matching.ReceivedLog, offset int64){
+	// do nothing 
+}
+
 func (t *TickMaker) OnOpenLOg(log *matching.OpenLog, offset int64){
 	// do nothing 
 }
@@ -60,6 +69,26 @@ func (t *TickMaker) OnDoneLog(log *matching.DoneLog, offset int64){
 	// do nothing 
 }
 
+func (t *TickMaker) OnActivateLog(log *matching.ActivateLog, offset int64){
+	// do nothing 
+}
+
+func (t *TickMaker) OnChangeLog(log *matching.ChangeLog, offset int64){
+	// do nothing 
+}
+
+func (t *TickMaker) OnAmendLog(log *matching.AmendLog, offset int64){
+	// do nothing 
+}
+
+func (t *TickMaker) OnStatusLog(log *matching.StatusLog, offset int64){
+	// do nothing 
+}
+
+func (t *TickMaker) OnRejectedLog(log *matching.RejectedLog, offset int64){
+	// do nothing 
+}
+
 func (t *TickMaker) OnMatchLog(log *matching.MatchLog, offset int64){
 	for _, granularity := range minutes{
 		tickTime := log.Time.UTC().Truncate(time.Duration(granularity) * time.Minute).Unix() 
@@ -44,6 +44,15 @@ func (t *TradeMaker) Start(){
 	go t.runFlusher()
 }
 
+// Stop stops reading logs, what is buffered is still flushed
+func (t *TradeMaker) Stop(){
+	t.LogReader.Stop()
+}
+
+func (t *TradeMaker) OnReceivedLog(log *matching.ReceivedLog, offset int64){
+	// do nothing 
+}
+
 func (t *TradeMaker) OnOpenLOg(log *matching.OpenLog, offset int64){
 	// do nothing 
 }
@@ -52,6 +61,26 @@ func (t *TradeMaker) OnDoneLog(log *matching.DoneLog, offset int64){
 	// do nothing 
 }
 
+func (t *TradeMaker) OnActivateLog(log *matching.ActivateLog, offset int64){
+	// do nothing 
+}
+
+func (t *TradeMaker) OnChangeLog(log *matching.ChangeLog, offset int64){
+	// do nothing 
+}
+
+func (t *TradeMaker) OnAmendLog(log *matching.AmendLog, offset int64){
+	// do nothing 
+}
+
+func (t *TradeMaker) OnStatusLog(log *matching.StatusLog, offset int64){
+	// do nothing 
+}
+
+func (t *TradeMaker) OnRejectedLog(log *matching.RejectedLog, offset int64){
+	// do nothing 
+}
+
 func (t *TradeMaker) OnMatchLog(log *matching.MatchLog, offset int64){
 	t.TradeCh <- &entities.Trade{
 		TradeId: log.TradeId, 